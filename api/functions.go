@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Types of the parameters and results of function problems, "T[]" is an array of T (see worker/harness.go)
+var functionTypes = []string{"int", "long", "double", "bool", "string"}
+
+// FunctionSpec is the signature of a function problem (problem.function_signature). Instead of
+// reading stdin, solutions implement the function; each test input holds one JSON value per line
+// for each parameter and the expected output is the JSON value of the result.
+type FunctionSpec struct {
+	Name    string          `json:"name" yaml:"name"`
+	Params  []FunctionParam `json:"params" yaml:"params"`
+	Returns string          `json:"returns" yaml:"returns"`
+}
+
+type FunctionParam struct {
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type" yaml:"type"`
+}
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// checkFunction tells what is wrong with the signature of a function problem, nil for none
+func checkFunction(fn *FunctionSpec) error {
+	if fn == nil {
+		return nil
+	}
+	// main and the harness' own names would clash with the generated harness
+	if !identifierPattern.MatchString(fn.Name) || fn.Name == "main" || strings.HasPrefix(fn.Name, "harness") {
+		return fmt.Errorf("invalid function name %q", fn.Name)
+	}
+	if !validFunctionType(fn.Returns) {
+		return fmt.Errorf("unsupported return type %q. Supported types: %s and their arrays (T[])", fn.Returns, strings.Join(functionTypes, ", "))
+	}
+	seen := make(map[string]bool)
+	for _, p := range fn.Params {
+		if !identifierPattern.MatchString(p.Name) || strings.HasPrefix(p.Name, "harness") {
+			return fmt.Errorf("invalid parameter name %q", p.Name)
+		}
+		// Rust gets the names in snake_case
+		if seen[snakeCase(p.Name)] {
+			return fmt.Errorf("parameter %q is declared twice", p.Name)
+		}
+		seen[snakeCase(p.Name)] = true
+		if !validFunctionType(p.Type) {
+			return fmt.Errorf("unsupported type %q of parameter %s. Supported types: %s and their arrays (T[])", p.Type, p.Name, strings.Join(functionTypes, ", "))
+		}
+	}
+	return nil
+}
+
+func validFunctionType(t string) bool {
+	t = strings.TrimSuffix(t, "[]")
+	for _, known := range functionTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// functionJSON is the value of problem.function_signature, nil (NULL) for stdin/stdout problems
+func functionJSON(fn *FunctionSpec) *string {
+	if fn == nil {
+		return nil
+	}
+	data, _ := json.Marshal(fn)
+	s := string(data)
+	return &s
+}
+
+// parseFunction reads problem.function_signature
+func parseFunction(data []byte) (*FunctionSpec, error) {
+	if data == nil {
+		return nil, nil
+	}
+	var fn FunctionSpec
+	if err := json.Unmarshal(data, &fn); err != nil {
+		return nil, fmt.Errorf("invalid function signature: %v", err)
+	}
+	return &fn, nil
+}
+
+// problemFunction reads the signature of a problem, nil if its solutions read stdin
+func problemFunction(problemID string) (*FunctionSpec, error) {
+	var data []byte
+	if err := db.QueryRow(ctx, `SELECT function_signature FROM problem WHERE problem_id = $1`, problemID).Scan(&data); err != nil {
+		return nil, err
+	}
+	return parseFunction(data)
+}
+
+// starterLanguage writes the empty function of a problem in one language, the way the worker's
+// harness of that language calls it
+type starterLanguage struct {
+	types  map[string]string // type names of the language, by function type
+	array  func(element string) string
+	method func(fn *FunctionSpec, s starterLanguage) string
+}
+
+func (s starterLanguage) typeName(t string) string {
+	if strings.HasSuffix(t, "[]") {
+		return s.array(s.types[strings.TrimSuffix(t, "[]")])
+	}
+	return s.types[t]
+}
+
+// params writes the parameters of fn with format (name, type), separated by commas
+func (s starterLanguage) params(fn *FunctionSpec, format func(name, typeName string) string) string {
+	params := make([]string, len(fn.Params))
+	for i, p := range fn.Params {
+		params[i] = format(p.Name, s.typeName(p.Type))
+	}
+	return strings.Join(params, ", ")
+}
+
+// Starter code by language id (languages.json)
+var starters = map[string]starterLanguage{
+	"python": {
+		types: map[string]string{"int": "int", "long": "int", "double": "float", "bool": "bool", "string": "str"},
+		array: func(e string) string { return "List[" + e + "]" },
+		method: func(fn *FunctionSpec, s starterLanguage) string {
+			params := s.params(fn, func(name, t string) string { return name + ": " + t })
+			if params != "" {
+				params = ", " + params
+			}
+			return fmt.Sprintf("from typing import List\n\n\nclass Solution:\n    def %s(self%s) -> %s:\n        pass\n",
+				fn.Name, params, s.typeName(fn.Returns))
+		},
+	},
+	"javascript": {
+		types: map[string]string{"int": "number", "long": "number", "double": "number", "bool": "boolean", "string": "string"},
+		array: func(e string) string { return e + "[]" },
+		method: func(fn *FunctionSpec, s starterLanguage) string {
+			var doc strings.Builder
+			doc.WriteString("/**\n")
+			for _, p := range fn.Params {
+				fmt.Fprintf(&doc, " * @param {%s} %s\n", s.typeName(p.Type), p.Name)
+			}
+			fmt.Fprintf(&doc, " * @return {%s}\n */\n", s.typeName(fn.Returns))
+			return fmt.Sprintf("%sfunction %s(%s) {\n\n}\n", doc.String(), fn.Name, s.params(fn, func(name, _ string) string { return name }))
+		},
+	},
+	"go": {
+		types: map[string]string{"int": "int", "long": "int64", "double": "float64", "bool": "bool", "string": "string"},
+		array: func(e string) string { return "[]" + e },
+		method: func(fn *FunctionSpec, s starterLanguage) string {
+			return fmt.Sprintf("func %s(%s) %s {\n\n}\n", fn.Name,
+				s.params(fn, func(name, t string) string { return name + " " + t }), s.typeName(fn.Returns))
+		},
+	},
+	"cpp": {
+		types: map[string]string{"int": "int", "long": "long long", "double": "double", "bool": "bool", "string": "string"},
+		array: func(e string) string { return "vector<" + e + ">" },
+		method: func(fn *FunctionSpec, s starterLanguage) string {
+			params := s.params(fn, func(name, t string) string {
+				if strings.HasPrefix(t, "vector<") {
+					t += "&"
+				}
+				return t + " " + name
+			})
+			return fmt.Sprintf("class Solution {\npublic:\n    %s %s(%s) {\n\n    }\n};\n", s.typeName(fn.Returns), fn.Name, params)
+		},
+	},
+	"c": {
+		types: map[string]string{"int": "int", "long": "long long", "double": "double", "bool": "bool", "string": "char*"},
+		array: func(e string) string { return e + "*" },
+		method: func(fn *FunctionSpec, s starterLanguage) string {
+			// Arrays come with their length, array results give theirs in returnSize
+			var params []string
+			for _, p := range fn.Params {
+				params = append(params, s.typeName(p.Type)+" "+p.Name)
+				if strings.HasSuffix(p.Type, "[]") {
+					params = append(params, "int "+p.Name+"Size")
+				}
+			}
+			note := ""
+			if strings.HasSuffix(fn.Returns, "[]") {
+				params = append(params, "int* returnSize")
+				note = "/**\n * Note: The returned array must be malloced, set *returnSize to its length.\n */\n"
+			}
+			return fmt.Sprintf("%s%s %s(%s) {\n\n}\n", note, s.typeName(fn.Returns), fn.Name, strings.Join(params, ", "))
+		},
+	},
+	"java": {
+		types: map[string]string{"int": "int", "long": "long", "double": "double", "bool": "boolean", "string": "String"},
+		array: func(e string) string { return e + "[]" },
+		method: func(fn *FunctionSpec, s starterLanguage) string {
+			return fmt.Sprintf("class Solution {\n    public %s %s(%s) {\n\n    }\n}\n", s.typeName(fn.Returns), fn.Name,
+				s.params(fn, func(name, t string) string { return t + " " + name }))
+		},
+	},
+	"csharp": {
+		types: map[string]string{"int": "int", "long": "long", "double": "double", "bool": "bool", "string": "string"},
+		array: func(e string) string { return e + "[]" },
+		method: func(fn *FunctionSpec, s starterLanguage) string {
+			return fmt.Sprintf("public class Solution {\n    public %s %s(%s) {\n\n    }\n}\n", s.typeName(fn.Returns), fn.Name,
+				s.params(fn, func(name, t string) string { return t + " " + name }))
+		},
+	},
+	"rust": {
+		types: map[string]string{"int": "i32", "long": "i64", "double": "f64", "bool": "bool", "string": "String"},
+		array: func(e string) string { return "Vec<" + e + ">" },
+		method: func(fn *FunctionSpec, s starterLanguage) string {
+			// snake_case, as the worker's harness calls it
+			return fmt.Sprintf("impl Solution {\n    pub fn %s(%s) -> %s {\n\n    }\n}\n", snakeCase(fn.Name),
+				s.params(fn, func(name, t string) string { return snakeCase(name) + ": " + t }), s.typeName(fn.Returns))
+		},
+	},
+}
+
+// snakeCase writes a camelCase identifier in snake_case, the Rust style for functions:
+// twoSum -> two_sum, isValidBST -> is_valid_bst. Identifiers are ASCII.
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c >= 'A' && c <= 'Z' {
+			prevLower := i > 0 && (name[i-1] >= 'a' && name[i-1] <= 'z' || name[i-1] >= '0' && name[i-1] <= '9')
+			acronymEnd := i > 0 && name[i-1] >= 'A' && name[i-1] <= 'Z' && i+1 < len(name) && name[i+1] >= 'a' && name[i+1] <= 'z'
+			if prevLower || acronymEnd {
+				b.WriteByte('_')
+			}
+			c += 'a' - 'A'
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// starterCode is the empty function of a problem in each registered language that supports it
+func starterCode(fn *FunctionSpec) map[string]string {
+	code := make(map[string]string)
+	for _, lang := range languageList {
+		if s, ok := starters[lang.ID]; ok {
+			code[lang.ID] = s.method(fn, s)
+		}
+	}
+	return code
+}
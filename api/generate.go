@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Program is a helper program of a problem: a test generator or an input validator
+type Program struct {
+	Language string `json:"language"`
+	Code     string `json:"code"`
+}
+
+// GenerateFormat is the body of POST /admin/problems/{id}/generate. The generator and the validator
+// are stored with the problem; when omitted, the stored ones are used.
+type GenerateFormat struct {
+	Generator *Program `json:"generator"`
+	Validator *Program `json:"validator"` // optional: exits with 0 if the input on stdin is valid
+	Tests     []string `json:"tests"`     // generator arguments of each test, e.g. ["1 10", "2 1000"]
+}
+
+// Largest number of tests a generation job may produce, same as the worker
+const maxGeneratedTests = 200
+
+// validProgram checks a helper program of an upload, answering 400 if it is incomplete
+func validProgram(w http.ResponseWriter, role string, p *Program) bool {
+	if p == nil {
+		return true
+	}
+	if p.Language == "" || p.Code == "" {
+		http.Error(w, fmt.Sprintf("The %s needs its language and code", role), http.StatusBadRequest)
+		return false
+	}
+	if _, ok := languages[p.Language]; !ok {
+		http.Error(w, fmt.Sprintf("Unsupported %s language. Supported languages: %s", role, supportedLanguages()), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// generateTestCasesHandler queues a generation job: the worker runs the generator with each list of
+// arguments, checks the inputs with the validator, computes the outputs with the reference solution
+// and stores them as test cases, replacing the previously generated ones
+func generateTestCasesHandler(w http.ResponseWriter, r *http.Request) {
+	problemID, ok := problemExists(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	id := strconv.Itoa(problemID)
+
+	var req GenerateFormat
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if len(req.Tests) == 0 || len(req.Tests) > maxGeneratedTests {
+		http.Error(w, fmt.Sprintf("tests must list the generator arguments of 1 to %d tests", maxGeneratedTests), http.StatusBadRequest)
+		return
+	}
+	if !validProgram(w, "generator", req.Generator) || !validProgram(w, "validator", req.Validator) {
+		return
+	}
+
+	// Keep the new programs, and use the stored ones for what was not sent (NULL keeps the column)
+	var generatorLanguage, generatorCode, validatorLanguage, validatorCode *string
+	if req.Generator != nil {
+		generatorLanguage, generatorCode = &req.Generator.Language, &req.Generator.Code
+	}
+	if req.Validator != nil {
+		validatorLanguage, validatorCode = &req.Validator.Language, &req.Validator.Code
+	}
+	var generator, validator, reference Program
+	err := db.QueryRow(ctx, `
+		UPDATE problem
+		SET generator_language = COALESCE($1, generator_language), generator_code = COALESCE($2, generator_code),
+			validator_language = COALESCE($3, validator_language), validator_code = COALESCE($4, validator_code)
+		WHERE problem_id = $5
+		RETURNING COALESCE(generator_language, ''), COALESCE(generator_code, ''),
+			COALESCE(validator_language, ''), COALESCE(validator_code, ''),
+			COALESCE(reference_language, ''), COALESCE(reference_code, '')`,
+		generatorLanguage, generatorCode, validatorLanguage, validatorCode, problemID,
+	).Scan(&generator.Language, &generator.Code, &validator.Language, &validator.Code, &reference.Language, &reference.Code)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update problem: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if generator.Code == "" {
+		http.Error(w, "The problem has no generator", http.StatusBadRequest)
+		return
+	}
+	if reference.Code == "" {
+		http.Error(w, "The problem needs a reference solution to compute the outputs", http.StatusBadRequest)
+		return
+	}
+
+	timeLimit, memoryLimit, checker, err := problemJudging(id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve problem: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if checker.Mode == CheckerInteractive {
+		http.Error(w, "Interactive problems cannot generate test outputs with the reference solution", http.StatusBadRequest)
+		return
+	}
+	function, err := problemFunction(id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve problem: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	job := Job{
+		ID:            uuid.NewString(),
+		Language:      reference.Language,
+		Code:          reference.Code,
+		Timestamp:     time.Now(),
+		ProblemID:     id,
+		TimeLimit:     timeLimit,
+		MemoryLimit:   memoryLimit,
+		Checker:       checker,
+		Function:      function,
+		Mode:          ModeGenerate,
+		Generator:     &generator,
+		GeneratorArgs: req.Tests,
+	}
+	if validator.Code != "" {
+		job.Validator = &validator
+	}
+
+	if err := enqueueJob(job); err != nil {
+		log.Printf("Failed to enqueue job %s: %v", job.ID, err)
+		http.Error(w, "Failed to enqueue job", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Generating %d tests for problem %d (job %s)", len(req.Tests), problemID, job.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "queued", "job_id": job.ID})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"leetcode-clone/registry"
+)
+
+// Language is one entry of the language registry (languages.json), shared with the worker
+type Language = registry.Language
+
+// Languages in registry order (for GET /languages) and by id
+var (
+	languageList []Language
+	languages    map[string]Language
+)
+
+// loadLanguages reads the language registry, see registry.Load. It checks the languages the way
+// the worker does, so the API accepts no language the workers would reject.
+func loadLanguages() error {
+	list, byID, err := registry.Load()
+	if err != nil {
+		return err
+	}
+	languageList, languages = list, byID
+	return nil
+}
+
+// languageInfo is what GET /languages tells about a language: how the code is built and run, but
+// not the executor's image and sandbox
+type languageInfo struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Version       string `json:"version"`
+	SourceFile    string `json:"source_file"`
+	Compile       string `json:"compile,omitempty"`
+	Run           string `json:"run"`
+	TimeLimitMs   int    `json:"time_limit_ms"`
+	MemoryLimitMB int    `json:"memory_limit_mb"`
+}
+
+// supportedLanguages lists the language ids, for error messages
+func supportedLanguages() string {
+	ids := make([]string, len(languageList))
+	for i, lang := range languageList {
+		ids[i] = lang.ID
+	}
+	return strings.Join(ids, ", ")
+}
+
+// languagesHandler returns the languages submissions can be written in
+func languagesHandler(w http.ResponseWriter, r *http.Request) {
+	infos := make([]languageInfo, len(languageList))
+	for i, lang := range languageList {
+		infos[i] = languageInfo{
+			ID:            lang.ID,
+			Name:          lang.Name,
+			Version:       lang.Version,
+			SourceFile:    lang.SourceFile,
+			Compile:       lang.Compile,
+			Run:           lang.Run,
+			TimeLimitMs:   lang.TimeLimitMs,
+			MemoryLimitMB: lang.MemoryLimitMB,
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(infos)
+}
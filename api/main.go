@@ -1,7 +1,6 @@
 package main
 
 import (
-	"archive/zip"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -10,7 +9,6 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"regexp"
 	"strings"
 	"time"
 
@@ -52,6 +50,24 @@ type UserData struct {
 	Admin  bool   `json:"admin"`
 }
 
+// Modes of /execute for a problem: run the sample tests, or submit against every test (recorded)
+const (
+	ModeRun      = "run"
+	ModeSubmit   = "submit"
+	ModeValidate = "validate" // the reference solution of a problem, see validateProblem
+	ModeGenerate = "generate" // test case generation, see generateTestCasesHandler
+	ModeStress   = "stress"   // compare the code to a brute-force solution on generated inputs
+)
+
+// Largest number of seeds of a stress test and largest first seed, same as the worker
+const (
+	maxStressSeeds = 1000
+	maxStressSeed  = 1000000000
+)
+
+// Largest custom input accepted for a playground run
+const maxStdinBytes = 1 << 20
+
 type Job struct {
 	ID        string    `json:"id"`
 	Language  string    `json:"language"`
@@ -62,6 +78,22 @@ type Job struct {
 	Outputs   []string   `json:"outputs"`
 	UserID    string     `json:"user_id,omitempty"` // Optional user ID for submissions
 	ProblemID string     `json:"problem_id,omitempty"` // Optional problem ID for submissions
+	TimeLimit   int      `json:"time_limit_ms,omitempty"`   // CPU time per test case, from problem.timelimit
+	MemoryLimit int      `json:"memory_limit_mb,omitempty"` // Memory per test case, from problem.memorylimit
+	Checker    CheckerSpec `json:"checker"`       // How outputs are compared
+	RunAll     bool      `json:"run_all"`          // Keep running after the first failing test
+	Hidden     []bool    `json:"hidden,omitempty"` // Per test: hidden from the user
+	ShowHidden bool      `json:"show_hidden"`      // The user may see hidden tests (admins)
+	Stdin      string    `json:"stdin,omitempty"`  // Input of a playground run
+	Mode       string    `json:"mode,omitempty"`   // ModeRun or ModeSubmit, "" for the playground
+	Subtasks   []Subtask `json:"subtasks,omitempty"` // Scoring groups of the tests, none = all or nothing
+	Generator     *Program `json:"generator,omitempty"`      // ModeGenerate, ModeStress: writes an input from its arguments
+	Validator     *Program `json:"validator,omitempty"`      // ModeGenerate: checks the inputs, optional
+	GeneratorArgs []string `json:"generator_args,omitempty"` // ModeGenerate: arguments of each test
+	Brute         *Program `json:"brute,omitempty"`          // ModeStress: solution the code is compared to
+	Seeds         int      `json:"seeds,omitempty"`          // ModeStress: number of seeds to try
+	Seed          int      `json:"seed,omitempty"`           // ModeStress: first seed, random if 0
+	Function      *FunctionSpec `json:"function,omitempty"`  // Function problems: the code is wrapped in a harness
 
 }
 
@@ -70,13 +102,37 @@ type JobResult struct {
 	Status    string    `json:"status"`
 	Output    string    `json:"output"`
 	Error     string    `json:"error"`
-	ExecTime  int64     `json:"exec_time_ms"`
+	ExecTime  int64     `json:"exec_time_ms"` // Wall time of the whole job
+	CPUTime   int64     `json:"cpu_time_ms"`  // CPU time of the program, the most any test used
+	MemoryKB  int64     `json:"memory_kb"`    // Peak memory of the program, the most any test used
 	Timestamp time.Time `json:"timestamp"`
 	TestCases int       `json:"test_cases"` // Number of test cases passed
 	TotalCases int       `json:"total_cases"` // Total number of test cases
 	UserID	   string    `json:"user_id"`
 	ProblemID string    `json:"problem_id"`
 	Language string    `json:"language"` // Language used for the submission
+	Verdict  string    `json:"verdict"`          // AC, WA, CE, RE, TLE, MLE, OLE, OK or IE
+	ExitCode int       `json:"exit_code"`        // Exit code of the failing (or last) run
+	Signal   string    `json:"signal,omitempty"` // Signal that killed the program, e.g. SIGSEGV
+	Stderr   string    `json:"stderr,omitempty"` // Stderr of the failing run or compiler output
+	Tests    []TestCaseResult `json:"tests,omitempty"` // Per-test results
+	Score    float64   `json:"score"`                // Percentage of the problem points earned (0-100)
+	Subtasks []SubtaskResult `json:"subtasks,omitempty"` // Per-subtask scores
+	Seed     int       `json:"seed,omitempty"`   // Stress test: first seed tried, to repeat it
+}
+
+// Outcome of one test case, hidden tests come without input/outputs for non-admins
+type TestCaseResult struct {
+	Index     int    `json:"index"`
+	Verdict   string `json:"verdict"`
+	TimeMs    int64  `json:"time_ms"`     // Wall time, including the executor overhead
+	CPUTimeMs int64  `json:"cpu_time_ms"` // CPU time of the program
+	MemoryKB  int64  `json:"memory_kb"`   // Peak memory of the program
+	Hidden    bool   `json:"hidden"`
+	Input     string `json:"input,omitempty"`
+	Expected  string `json:"expected,omitempty"`
+	Actual    string `json:"actual,omitempty"`
+	Message   string `json:"message,omitempty"` // Checker comment
 }
 
 type Reward struct {
@@ -135,6 +191,9 @@ type Problem struct {
 	Question    string   `json:"question"`
 	Inputs      []string `json:"inputs"`
 	Outputs     []string `json:"outputs"`
+	Interactive bool     `json:"interactive"` // the solution talks to an interactor instead of reading a whole input
+	Function    *FunctionSpec     `json:"function,omitempty"`     // function problems: the signature to implement
+	StarterCode map[string]string `json:"starter_code,omitempty"` // function problems: empty function by language
 }
 
 type UploadProblemFormat struct {
@@ -144,7 +203,10 @@ type UploadProblemFormat struct {
 	SampleTests string `json:"sampletests"`
 	MemoryLimit int    `json:"memorylimit"`
 	Question    string `json:"question"`
-	// Tags        []string `json:"tags"`
+	Tags        []string `json:"tags"`
+	ReferenceLanguage string `json:"reference_language"` // optional reference solution, run on every test case
+	ReferenceCode     string `json:"reference_code"`
+	Function    *FunctionSpec `json:"function"` // optional, makes it a function problem
 }
 
 type EditProblemFormat struct {
@@ -155,11 +217,52 @@ type EditProblemFormat struct {
 	SampleTests string `json:"sampletests"`
 	MemoryLimit int    `json:"memorylimit"`
 	Question    string `json:"question"`
+	Tags        []string `json:"tags"` // null keeps the current tags
+	ReferenceLanguage string `json:"reference_language"` // empty keeps the current reference solution
+	ReferenceCode     string `json:"reference_code"`
+	Function    *FunctionSpec `json:"function"` // null keeps the current signature
+}
+
+// Checker modes (see worker/checker.go)
+const (
+	CheckerExact       = "exact"
+	CheckerTokens      = "tokens"
+	CheckerFloat       = "float"
+	CheckerUnordered   = "unordered"
+	CheckerSpecial     = "special"
+	CheckerInteractive = "interactive" // the checker program is an interactor talking to the solution
+)
+
+func validCheckerMode(mode string) bool {
+	switch mode {
+	case CheckerExact, CheckerTokens, CheckerFloat, CheckerUnordered, CheckerSpecial, CheckerInteractive:
+		return true
+	}
+	return false
+}
+
+// Output checker of a problem, see /admin/uploadChecker
+type CheckerSpec struct {
+	Mode       string  `json:"mode"`                  // exact, tokens, float, unordered, special or interactive
+	AbsEpsilon float64 `json:"abs_epsilon,omitempty"` // float mode
+	RelEpsilon float64 `json:"rel_epsilon,omitempty"` // float mode
+	Language   string  `json:"language,omitempty"`    // special and interactive modes: checker / interactor language
+	Code       string  `json:"code,omitempty"`        // special and interactive modes: checker / interactor source
+}
+
+type CheckerFormat struct {
+	ProblemID int `json:"problem_id"`
+	CheckerSpec
 }
 
 type TestCaseFiles struct {
-	In  string `json:"in"`
-	Out string `json:"out"`
+	Number  int    `json:"number"` // N of N.in / N.out
+	In      string `json:"in"`
+	Out     string `json:"out"`
+	Sample  bool   `json:"sample"`            // from the samples/ folder of the zip
+	Subtask *int   `json:"subtask,omitempty"` // from a subtaskN/ folder of the zip
+	hasIn   bool
+	hasOut  bool
 }
 
 type Badge struct {
@@ -199,15 +302,6 @@ func connectToDB() {
 	}
 }
 
-func create_submission(userID string, problemID string, status bool, lang string, execTime int64, output string) error {
-	_, err := db.Exec(
-		ctx,
-		"CALL create_submission($1, $2, $3, $4, $5, $6)",
-		userID, problemID, status, lang, execTime, output,
-	)
-	return err
-}
-
 func executeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -220,6 +314,13 @@ func executeHandler(w http.ResponseWriter, r *http.Request) {
 		Code      string              `json:"code"`
 		UserId    string              `json:"userId"`
 		ProblemID string 			  `json:"probId"`
+		Mode      string              `json:"mode"`   // "run" (sample tests) or "submit" (all tests, recorded)
+		RunAll    *bool               `json:"runAll"` // Run every test case, default true for submissions
+		Stdin     string              `json:"stdin"`  // Input for playground runs ("Run with custom input")
+		Brute     *Program            `json:"brute"`     // Stress test: brute-force solution
+		Generator *Program            `json:"generator"` // Stress test: writes an input from a seed
+		Seeds     int                 `json:"seeds"`     // Stress test: number of seeds, 100 by default
+		Seed      int                 `json:"seed"`      // Stress test: first seed, random by default
 		Inputs  []string
 		Outputs []string
 
@@ -231,33 +332,83 @@ func executeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 		log.Printf("Received execution request: %+v", req)
-	//find testcases
-		if req.UserId != "" && req.ProblemID != "" {
-		rows, err := db.Query(ctx, `
-			SELECT t.tin, t.tout
-			FROM testcases t
-			JOIN problem p ON p.problem_id = t.problem_id
-			WHERE p.problem_id = $1;
-		`, req.ProblemID)
 
+	// Submissions (userId and probId) used to be the only mode, keep it as the default
+	if req.Mode == "" && req.UserId != "" && req.ProblemID != "" {
+		req.Mode = ModeSubmit
+	}
+	// Run and submit judge the problem's own tests: extra tests would shift the subtask indexes
+	if (req.Mode == ModeRun || req.Mode == ModeSubmit) && (len(req.Inputs) > 0 || len(req.Outputs) > 0) {
+		http.Error(w, "Inputs and Outputs are not accepted by run and submit, they use the problem's tests", http.StatusBadRequest)
+		return
+	}
+	switch req.Mode {
+	case "":
+	case ModeRun:
+		if req.ProblemID == "" {
+			http.Error(w, "probId is required to run the sample tests", http.StatusBadRequest)
+			return
+		}
+	case ModeSubmit:
+		if req.UserId == "" || req.ProblemID == "" {
+			http.Error(w, "userId and probId are required to submit", http.StatusBadRequest)
+			return
+		}
+		if req.Stdin != "" {
+			http.Error(w, "stdin is not accepted when submitting, submissions are judged on the problem's tests", http.StatusBadRequest)
+			return
+		}
+	case ModeStress:
+		if req.Brute == nil || req.Generator == nil {
+			http.Error(w, "brute and generator are required for a stress test", http.StatusBadRequest)
+			return
+		}
+		if !validProgram(w, "brute-force solution", req.Brute) || !validProgram(w, "generator", req.Generator) {
+			return
+		}
+		if req.Seeds < 0 || req.Seeds > maxStressSeeds {
+			http.Error(w, fmt.Sprintf("seeds must be between 0 (default) and %d", maxStressSeeds), http.StatusBadRequest)
+			return
+		}
+		if req.Seed < 0 || req.Seed > maxStressSeed {
+			http.Error(w, fmt.Sprintf("seed must be between 0 (random) and %d", maxStressSeed), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Unsupported mode. Supported modes: run, submit, stress", http.StatusBadRequest)
+		return
+	}
+
+	//find testcases: only the samples when running, unless it is a run with custom input
+	var subtasks []Subtask
+	if req.Mode == ModeSubmit || (req.Mode == ModeRun && req.Stdin == "") {
+		if _, ok := problemExists(w, req.ProblemID); !ok {
+			return
+		}
+		inputs, outputs, testSubtasks, err := problemTestCases(req.ProblemID, req.Mode == ModeSubmit)
 		if err != nil {
-			log.Printf("Warning: failed to fetch testcases for problem %s: %v", req.ProblemID, err)
-			// Continue without testcases
-		} else {
-			defer rows.Close()
-
-			for rows.Next() {
-				var input, output string
-				if err := rows.Scan(&input, &output); err != nil {
-					log.Printf("Warning: error reading testcase row: %v", err)
-					continue // Skip this testcase
-				}
-				req.Inputs = append(req.Inputs, input)
-				req.Outputs = append(req.Outputs, output)
+			log.Printf("Failed to fetch testcases for problem %s: %v", req.ProblemID, err)
+			http.Error(w, "Failed to retrieve test cases", http.StatusInternalServerError)
+			return
+		}
+		// Without tests the job would be a playground run, and a submission accepted on nothing
+		if len(inputs) == 0 {
+			if req.Mode == ModeSubmit {
+				http.Error(w, "The problem has no test cases yet", http.StatusConflict)
+			} else {
+				http.Error(w, "The problem has no sample tests, run it with custom input (stdin)", http.StatusConflict)
 			}
+			return
+		}
+		req.Inputs, req.Outputs = inputs, outputs
 
-			if err := rows.Err(); err != nil {
-				log.Printf("Warning: error iterating over testcases: %v", err)
+		// Submissions are scored by subtask, if the problem has them
+		if req.Mode == ModeSubmit {
+			subtasks, err = problemSubtasks(req.ProblemID, testSubtasks)
+			if err != nil {
+				log.Printf("Failed to fetch subtasks for problem %s: %v", req.ProblemID, err)
+				http.Error(w, "Failed to retrieve subtasks", http.StatusInternalServerError)
+				return
 			}
 		}
 	}
@@ -267,9 +418,34 @@ func executeHandler(w http.ResponseWriter, r *http.Request) {
 		fmt.Printf("Received execution request from user: %s\n", req.UserId)
 	}
 
+	// Problem limits and output checker
+	var timeLimit, memoryLimit int
+	var checker CheckerSpec
+	var function *FunctionSpec
+	if req.ProblemID != "" {
+		var err error
+		timeLimit, memoryLimit, checker, err = problemJudging(req.ProblemID)
+		if err != nil && err != pgx.ErrNoRows {
+			log.Printf("Warning: failed to fetch limits for problem %s: %v", req.ProblemID, err)
+		}
+		function, err = problemFunction(req.ProblemID)
+		if err != nil && err != pgx.ErrNoRows {
+			log.Printf("Warning: failed to fetch the function signature of problem %s: %v", req.ProblemID, err)
+		}
+	}
+	if req.Mode == ModeStress && checker.Mode == CheckerInteractive {
+		http.Error(w, "Stress tests are not available for interactive problems", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Stdin) > maxStdinBytes {
+		http.Error(w, fmt.Sprintf("stdin is too large (max %d bytes)", maxStdinBytes), http.StatusBadRequest)
+		return
+	}
+
 	// Validate language
-	if req.Language != "python" && req.Language != "javascript" && req.Language != "cpp" && req.Language != "csharp" {
-		http.Error(w, "Unsupported language. Supported languages: python, javascript, cpp, c#", http.StatusBadRequest)
+	if _, ok := languages[req.Language]; !ok {
+		http.Error(w, "Unsupported language. Supported languages: "+supportedLanguages(), http.StatusBadRequest)
 		return
 	}
 
@@ -278,35 +454,101 @@ func executeHandler(w http.ResponseWriter, r *http.Request) {
 		ID:        uuid.NewString(),
 		Language:  req.Language,
 		Code:      req.Code,
+		Stdin:     req.Stdin,
 		Timestamp: time.Now(),
 		Inputs:    req.Inputs,
 		Outputs:   req.Outputs,
-	}
-	if req.UserId != "" {
+		TimeLimit:   timeLimit,
+		MemoryLimit: memoryLimit,
+		Checker:     checker,
+		Function:    function,
+	}
+	job.Mode = req.Mode
+	job.Subtasks = subtasks
+	if req.Mode == ModeRun {
+		// Samples are public: every test is shown
+		job.UserID = req.UserId
+		job.ProblemID = req.ProblemID
+		job.RunAll = true
+	} else if req.Mode == ModeStress {
+		// Compared on fresh inputs, with the problem's limits and checker if there is one
+		job.UserID = req.UserId
+		job.ProblemID = req.ProblemID
+		job.Brute = req.Brute
+		job.Generator = req.Generator
+		job.Seeds = req.Seeds
+		job.Seed = req.Seed
+	} else if req.UserId != "" {
 		job.UserID = req.UserId
 		job.ProblemID = req.ProblemID
-	}
 
-	jobData, err := json.Marshal(job)
-	if err != nil {
-		http.Error(w, "Error processing job", http.StatusInternalServerError)
-		return
+		// Submissions run every test unless asked otherwise
+		job.RunAll = req.ProblemID != ""
+		if req.RunAll != nil {
+			job.RunAll = *req.RunAll
+		}
+
+		// Stored testcases are hidden, only admins get to see them in the results
+		job.Hidden = make([]bool, len(job.Inputs))
+		for i := range job.Hidden {
+			job.Hidden[i] = true
+		}
+		if err := db.QueryRow(ctx, `SELECT COALESCE(is_admin, false) FROM "User" WHERE user_id = $1`, req.UserId).Scan(&job.ShowHidden); err != nil && err != pgx.ErrNoRows {
+			log.Printf("Warning: failed to check admin status of user %s: %v", req.UserId, err)
+		}
 	}
 
-	// Push job to Redis queue
-	if err := rdb.LPush(ctx, "code_jobs", jobData).Err(); err != nil {
+	if err := enqueueJob(job); err != nil {
+		log.Printf("Failed to enqueue job %s: %v", job.ID, err)
 		http.Error(w, "Failed to enqueue job", http.StatusInternalServerError)
 		return
 	}
-	log.Printf("redis done:")
-	
 
-	if err := rdb.HSet(ctx, "job:"+job.ID, "status", "pending").Err(); err != nil {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"job_id": "%s"}`, job.ID)
+}
+
+// problemJudging reads the limits (time in ms, memory in MB) and the output checker of a problem
+func problemJudging(problemID string) (int, int, CheckerSpec, error) {
+	// problem.timelimit is in seconds
+	var timeLimit, memoryLimit int
+	var checker CheckerSpec
+	err := db.QueryRow(ctx, `
+		SELECT COALESCE(timelimit, 0), COALESCE(memorylimit, 0),
+			checker_mode, COALESCE(checker_abs_epsilon, 0), COALESCE(checker_rel_epsilon, 0),
+			COALESCE(checker_language, ''), COALESCE(checker_code, '')
+		FROM problem
+		WHERE problem_id = $1;
+	`, problemID).Scan(&timeLimit, &memoryLimit,
+		&checker.Mode, &checker.AbsEpsilon, &checker.RelEpsilon, &checker.Language, &checker.Code)
+	return timeLimit * 1000, memoryLimit, checker, err
+}
+
+// enqueueJob records a job as queued and pushes it to the worker queue
+func enqueueJob(job Job) error {
+	jobData, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	// Record the job before it can be picked up, so the worker's updates come after it
+	pipe := rdb.TxPipeline()
+	pipe.HSet(ctx, "job:"+job.ID, "stage", StageQueued, "updated_at", time.Now().Format(time.RFC3339))
+	pipe.Expire(ctx, "job:"+job.ID, jobStatusTTL)
+	seq := pipe.Incr(ctx, queueSequence)
+	if _, err := pipe.Exec(ctx); err != nil {
 		log.Printf(" Failed to set job status for %s: %v", job.ID, err)
+	} else {
+		rdb.ZAdd(ctx, queuePositions, &redis.Z{Score: float64(seq.Val()), Member: job.ID})
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	fmt.Fprintf(w, `{"job_id": "%s"}`, job.ID)
+	// Push job to Redis queue
+	if err := rdb.LPush(ctx, "code_jobs", jobData).Err(); err != nil {
+		rdb.ZRem(ctx, queuePositions, job.ID)
+		rdb.Del(ctx, "job:"+job.ID)
+		return err
+	}
+	return nil
 }
 
 func resultHandler(w http.ResponseWriter, r *http.Request) {
@@ -322,16 +564,18 @@ func resultHandler(w http.ResponseWriter, r *http.Request) {
 	resultData, err := rdb.Get(ctx, "result:"+jobID).Result()
 	if err != nil {
 		if err == redis.Nil {
-			// Check if job exists but hasn't been processed yet
-			_, err := rdb.LRange(ctx, "code_jobs", 0, -1).Result()
+			// Job is still in queue or being processed
+			progress, err := jobProgress(jobID)
+			if err == redis.Nil {
+				http.Error(w, "Job not found", http.StatusNotFound)
+				return
+			}
 			if err != nil {
 				http.Error(w, "Error checking job status", http.StatusInternalServerError)
 				return
 			}
-
-			// Job is still in queue or being processed
 			w.Header().Set("Content-Type", "application/json")
-			fmt.Fprintf(w, `{"job_id": "%s", "status": "pending"}`, jobID)
+			json.NewEncoder(w).Encode(progress)
 			return
 		}
 		http.Error(w, "Error retrieving job result", http.StatusInternalServerError)
@@ -340,26 +584,41 @@ func resultHandler(w http.ResponseWriter, r *http.Request) {
 	// Return the result
 	w.Header().Set("Content-Type", "application/json")
 	w.Write([]byte(resultData))
+}
 
-	//if resultData with UserID and ProblemID exists, call procedure
-	if strings.Contains(resultData, `"user_id":`) && strings.Contains(resultData, `"problem_id":`) {
-		var job JobResult
-		if err := json.Unmarshal([]byte(resultData), &job); err != nil {
-			log.Printf("Error unmarshaling job result: %v", err)
-			return
-		}
+// Jobs the workers gave up on (lost too many times) and how often each job was lost, see worker/queue.go
+const (
+	deadLetterList = "code_jobs:dead"
+	attemptsHash   = "code_jobs:attempts"
+)
 
-		log.Printf("updating postgreSQL submission for user %s on problem %s", job.UserID, job.ProblemID)
-		var status bool = job.Status == "accept"
+type DeadJob struct {
+	Job      Job    `json:"job"`
+	Attempts int    `json:"attempts"`
+	Raw      string `json:"raw,omitempty"` // payload that could not be parsed
+}
 
-		// Call the procedure to handle the submission
-		if err := create_submission(job.UserID, job.ProblemID,status, job.Language,job.ExecTime,job.Output); err != nil {
-			log.Printf("Error handling submission: %v", err)
-		} else {
-			log.Printf("Submission processed successfully for user %s on problem %s", job.UserID, job.ProblemID)
+// getDeadJobsHandler lists the dead-letter jobs, newest first
+func getDeadJobsHandler(w http.ResponseWriter, r *http.Request) {
+	payloads, err := rdb.LRange(ctx, deadLetterList, 0, -1).Result()
+	if err != nil {
+		http.Error(w, "Error reading dead jobs", http.StatusInternalServerError)
+		return
+	}
+
+	deadJobs := make([]DeadJob, 0, len(payloads))
+	for _, payload := range payloads {
+		var dead DeadJob
+		if err := json.Unmarshal([]byte(payload), &dead.Job); err != nil {
+			dead.Raw = payload
+		} else if attempts, err := rdb.HGet(ctx, attemptsHash, dead.Job.ID).Int(); err == nil {
+			dead.Attempts = attempts
 		}
+		deadJobs = append(deadJobs, dead)
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deadJobs)
 }
 
 func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
@@ -491,12 +750,15 @@ func getLeaderboardProblem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Ranked by the CPU time of the program (then its memory). Submissions from before it was
+	// measured come last: their wall time of the whole job is not comparable
 	rows, err := db.Query(ctx, `
 		SELECT *
 		FROM (
 			SELECT DISTINCT ON (s.user_id)
 				u.name,
-				s.time AS execution_time
+				s.cpu_time_ms AS execution_time,
+				s.memory_kb
 			FROM
 				submission s
 			LEFT JOIN "User" u ON s.user_id = u.user_id
@@ -505,10 +767,12 @@ func getLeaderboardProblem(w http.ResponseWriter, r *http.Request) {
 				AND s.correct = true
 			ORDER BY
 				s.user_id,
-				s.time ASC
+				s.cpu_time_ms ASC NULLS LAST,
+				s.memory_kb ASC NULLS LAST
 		) AS fastest_per_user
 		ORDER BY
-			execution_time ASC;
+			execution_time ASC NULLS LAST,
+			memory_kb ASC NULLS LAST;
 	`, problemId)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to fetch leaderboard: %v", err), http.StatusInternalServerError)
@@ -518,14 +782,15 @@ func getLeaderboardProblem(w http.ResponseWriter, r *http.Request) {
 
 	type ProblemLeaderboard struct {
 		UserName string `json:"user_name"`
-		Time     int64  `json:"time"`
+		Time     *int64 `json:"time,omitempty"`      // CPU time in ms, unknown for older submissions
+		MemoryKB *int64 `json:"memory_kb,omitempty"` // peak memory, unknown for older submissions
 	}
 
 	var leaderboard []ProblemLeaderboard
 
 	for rows.Next() {
 		var entry ProblemLeaderboard
-		if err := rows.Scan(&entry.UserName, &entry.Time); err != nil {
+		if err := rows.Scan(&entry.UserName, &entry.Time, &entry.MemoryKB); err != nil {
 			http.Error(w, fmt.Sprintf("Failed to scan row: %v", err), http.StatusInternalServerError)
 			return
 		}
@@ -694,6 +959,8 @@ func getAllProblems(w http.ResponseWriter, r *http.Request) {
 			problem p
 		LEFT JOIN 
 			user_submissions us ON p.problem_id = us.problem_id
+		WHERE
+			p.published
 		GROUP BY 
 			p.problem_id, p.title, p.difficulty
 		ORDER BY 
@@ -756,6 +1023,8 @@ func getChallengeId(w http.ResponseWriter, r *http.Request) {
     	p.timelimit,
     	p.memorylimit,
     	p.tests,
+    	p.checker_mode = 'interactive',
+    	p.function_signature,
     	NULL AS solved
 	FROM 
     	problem p
@@ -771,11 +1040,19 @@ func getChallengeId(w http.ResponseWriter, r *http.Request) {
 	// Create a Problem struct
 	var problem Problem
 	if rows.Next() {
-		err := rows.Scan(&problem.ProblemID, &problem.Title, &problem.Difficulty, &problem.Question, &problem.Inputs, &problem.Outputs, &problem.TimeLimit, &problem.MemoryLimit, &problem.Tests, &problem.Solved)
+		var signature []byte
+		err := rows.Scan(&problem.ProblemID, &problem.Title, &problem.Difficulty, &problem.Question, &problem.Inputs, &problem.Outputs, &problem.TimeLimit, &problem.MemoryLimit, &problem.Tests, &problem.Interactive, &signature, &problem.Solved)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Failed to scan problem: %v", err), http.StatusInternalServerError)
 			return
 		}
+		if problem.Function, err = parseFunction(signature); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read problem: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if problem.Function != nil {
+			problem.StarterCode = starterCode(problem.Function)
+		}
 	} else {
 		// No problem found
 		http.Error(w, fmt.Sprintf("Problem with ID %s not found", probID), http.StatusNotFound)
@@ -801,10 +1078,20 @@ func uploadProblemStatement(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
+	if !validReference(w, problem.ReferenceLanguage, problem.ReferenceCode) {
+		return
+	}
+	if err := checkFunction(problem.Function); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid function signature: %v", err), http.StatusBadRequest)
+		return
+	}
+	// With a reference solution the problem is published once the solution passes its test cases
 	rows, err := db.Query(ctx,
-		`INSERT INTO problem (title, difficulty, timelimit, memorylimit, question, answer, inputs, outputs, tests)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING problem_id`,
-		problem.Title, problem.Difficulty, problem.TimeLimit, problem.MemoryLimit, problem.Question, " ", []string{}, []string{}, problem.SampleTests)
+		`INSERT INTO problem (title, difficulty, timelimit, memorylimit, question, answer, inputs, outputs, tests,
+			reference_language, reference_code, published, tags, function_signature)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), NULLIF($11, ''), $12, COALESCE($13, '{}'), $14) RETURNING problem_id`,
+		problem.Title, problem.Difficulty, problem.TimeLimit, problem.MemoryLimit, problem.Question, " ", []string{}, []string{}, problem.SampleTests,
+		problem.ReferenceLanguage, problem.ReferenceCode, problem.ReferenceCode == "", problem.Tags, functionJSON(problem.Function))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to insert problem: %v", err), http.StatusInternalServerError)
 		return
@@ -825,12 +1112,20 @@ func uploadProblemStatement(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Check the test cases (if any yet) with the reference solution
+	validationJobID := ""
+	if problemID != 0 {
+		validationJobID = revalidateProblem(problemID)
+	}
+
 	response := struct {
-		Status    string `json:"status"`
-		ProblemID int    `json:"problem_id"`
+		Status          string `json:"status"`
+		ProblemID       int    `json:"problem_id"`
+		ValidationJobID string `json:"validation_job_id,omitempty"`
 	}{
-		Status:    "success",
-		ProblemID: problemID,
+		Status:          "success",
+		ProblemID:       problemID,
+		ValidationJobID: validationJobID,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -847,10 +1142,21 @@ func editProblemStatement(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
+	if !validReference(w, problem.ReferenceLanguage, problem.ReferenceCode) {
+		return
+	}
+	if err := checkFunction(problem.Function); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid function signature: %v", err), http.StatusBadRequest)
+		return
+	}
 
 	rows, err := db.Query(ctx,
-		`UPDATE problem SET title = $1, difficulty = $2, timelimit = $3, memorylimit = $4, question = $5, inputs = $6, outputs = $7, tests = $8 WHERE problem_id = $9 RETURNING problem_id`,
-		problem.Title, problem.Difficulty, problem.TimeLimit, problem.MemoryLimit, problem.Question, []string{}, []string{}, problem.SampleTests, problem.ProblemID)
+		`UPDATE problem SET title = $1, difficulty = $2, timelimit = $3, memorylimit = $4, question = $5, inputs = $6, outputs = $7, tests = $8,
+			reference_language = COALESCE(NULLIF($10, ''), reference_language), reference_code = COALESCE(NULLIF($11, ''), reference_code),
+			tags = COALESCE($12, tags), function_signature = COALESCE($13, function_signature)
+		WHERE problem_id = $9 RETURNING problem_id`,
+		problem.Title, problem.Difficulty, problem.TimeLimit, problem.MemoryLimit, problem.Question, []string{}, []string{}, problem.SampleTests, problem.ProblemID,
+		problem.ReferenceLanguage, problem.ReferenceCode, problem.Tags, functionJSON(problem.Function))
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to update problem: %v", err), http.StatusInternalServerError)
 		return
@@ -870,12 +1176,20 @@ func editProblemStatement(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Check the test cases (if any yet) with the reference solution
+	validationJobID := ""
+	if problemID != 0 {
+		validationJobID = revalidateProblem(problemID)
+	}
+
 	response := struct {
-		Status    string `json:"status"`
-		ProblemID int    `json:"problem_id"`
+		Status          string `json:"status"`
+		ProblemID       int    `json:"problem_id"`
+		ValidationJobID string `json:"validation_job_id,omitempty"`
 	}{
-		Status:    "success",
-		ProblemID: problemID,
+		Status:          "success",
+		ProblemID:       problemID,
+		ValidationJobID: validationJobID,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -911,111 +1225,82 @@ func deleteProblem(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// uploadTestCases stores the test cases of a zip of N.in / N.out files: samples in a samples/ folder,
+// subtask tests in subtaskN/ folders. ?mode=replace replaces the existing test cases.
 func uploadTestCases(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
-	respondWithError := func(status int, message string) {
-		w.WriteHeader(status)
-		json.NewEncoder(w).Encode(map[string]string{"error": message})
-	}
-
 	problemID := r.URL.Query().Get("problemId")
 	if problemID == "" {
-		respondWithError(http.StatusBadRequest, "Missing problemId")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Missing problemId"})
 		return
 	}
 
-	err := r.ParseMultipartForm(10 << 20) // 10 MB
-	if err != nil {
-		fmt.Println("Error parsing form:", err)
-		respondWithError(http.StatusBadRequest, "No se pudo parsear el formulario")
+	id, ok := problemExists(w, problemID)
+	if !ok {
 		return
 	}
+	importTestCaseZip(w, r, id)
+}
 
-	file, handler, err := r.FormFile("file")
-	if err != nil {
-		fmt.Println("Error retrieving the file:", err)
-		respondWithError(http.StatusBadRequest, "Error al obtener el archivo")
+// uploadChecker sets how the outputs of a problem are judged: a comparison mode, or the
+// special judge / interactor program of the problem
+func uploadChecker(w http.ResponseWriter, r *http.Request) {
+	var checker CheckerFormat
+	if err := json.NewDecoder(r.Body).Decode(&checker); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
-
-	fmt.Println("Uploaded File:", handler.Filename)
 
-	var buf bytes.Buffer
-	_, err = io.Copy(&buf, file)
-	if err != nil {
-		fmt.Println("Error copying file to buffer:", err)
-		respondWithError(http.StatusInternalServerError, "Error leyendo el archivo")
+	switch checker.Mode {
+	case "exact", "tokens", "unordered":
+	case "float":
+		if checker.AbsEpsilon < 0 || checker.RelEpsilon < 0 {
+			http.Error(w, "Epsilon cannot be negative", http.StatusBadRequest)
+			return
+		}
+	case "special", "interactive":
+		if checker.Code == "" || checker.Language == "" {
+			http.Error(w, fmt.Sprintf("A %s checker needs its language and code", checker.Mode), http.StatusBadRequest)
+			return
+		}
+		if _, ok := languages[checker.Language]; !ok {
+			http.Error(w, "Unsupported checker language. Supported languages: "+supportedLanguages(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Unsupported checker mode. Supported modes: exact, tokens, float, unordered, special, interactive", http.StatusBadRequest)
 		return
 	}
 
-	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	tag, err := db.Exec(ctx, `
+		UPDATE problem
+		SET checker_mode = $1, checker_abs_epsilon = NULLIF($2, 0), checker_rel_epsilon = NULLIF($3, 0),
+			checker_language = NULLIF($4, ''), checker_code = NULLIF($5, '')
+		WHERE problem_id = $6`,
+		checker.Mode, checker.AbsEpsilon, checker.RelEpsilon, checker.Language, checker.Code, checker.ProblemID)
 	if err != nil {
-		fmt.Println("Error opening zip:", err)
-		respondWithError(http.StatusInternalServerError, "Error abriendo el archivo zip")
+		http.Error(w, fmt.Sprintf("Failed to update checker: %v", err), http.StatusInternalServerError)
 		return
 	}
-	testCases := make(map[string]*TestCaseFiles)
-	validNameRegex := regexp.MustCompile(`^\d+\.(in|out)$`)
-	for _, zipFile := range reader.File {
-		_, fileName := extractFileName(zipFile.Name)
-
-		// Solo procesar archivos que matcheen el patrón
-		if !validNameRegex.MatchString(fileName) {
-			continue
-		}
-
-		parts := strings.Split(fileName, ".")
-		if len(parts) != 2 {
-			continue
-		}
-		number := parts[0]
-		ext := parts[1]
-
-		zippedFile, err := zipFile.Open()
-		if err != nil {
-			fmt.Println("Error opening file inside zip:", err)
-			continue
-		}
-
-		content, err := io.ReadAll(zippedFile)
-		zippedFile.Close()
-		if err != nil {
-			fmt.Println("Error reading file inside zip:", err)
-			continue
-		}
-
-		// Inicializar si no existe
-		if _, ok := testCases[number]; !ok {
-			testCases[number] = &TestCaseFiles{}
-		}
-
-		// Guardar el contenido según sea .in o .out
-		if ext == "in" {
-			testCases[number].In = string(content)
-		} else if ext == "out" {
-			testCases[number].Out = string(content)
-		}
+	if tag.RowsAffected() == 0 {
+		http.Error(w, fmt.Sprintf("Problem with ID %d not found", checker.ProblemID), http.StatusNotFound)
+		return
 	}
+	revalidateProblem(checker.ProblemID)
 
-	for _, files := range testCases {
-		if files.In == "" || files.Out == "" {
-			continue
-		}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
+}
 
-		
-		_, err := db.Exec(ctx, `INSERT INTO testcases (problem_id, tin, tout) VALUES ($1, $2, $3)`, problemID, files.In, files.Out)
-		if err != nil {
-			fmt.Println("Error inserting test case into database:", err)
-			respondWithError(http.StatusInternalServerError, "Error inserting test case into database")
-			return
+// isSampleDir tells if a zip directory holds sample tests (a samples/ folder at any depth)
+func isSampleDir(dir string) bool {
+	for _, part := range strings.Split(dir, "/") {
+		if strings.EqualFold(part, "samples") {
+			return true
 		}
 	}
-
-	// Si todo fue exitoso
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Test cases procesados exitosamente"})
+	return false
 }
 
 func extractFileName(path string) (string, string) {
@@ -1460,6 +1745,10 @@ func main() {
 		os.Setenv("REDIS_ADDR", "localhost:6379")
 	}
 
+	if err := loadLanguages(); err != nil {
+		log.Fatalf("Failed to load languages: %v", err)
+	}
+
 	// Connect to the database
 	connectToDB()
 	defer db.Close()
@@ -1476,7 +1765,11 @@ func main() {
 
 	router.HandleFunc("/execute", executeHandler).Methods("POST")
 	router.HandleFunc("/result/{id}", resultHandler).Methods("GET")
+	router.HandleFunc("/result/{id}/stream", streamHandler).Methods("GET")
+	router.HandleFunc("/users/{id}/submissions", getUserSubmissionsHandler).Methods("GET")
+	router.HandleFunc("/submissions/{id}", getSubmissionHandler).Methods("GET")
 	router.HandleFunc("/health", healthCheckHandler).Methods("GET")
+	router.HandleFunc("/languages", languagesHandler).Methods("GET")
 	router.HandleFunc("/claim", claimHandler).Methods("POST")
 	router.HandleFunc("/rewards", getRewardsHandler).Methods("GET")
 	router.HandleFunc("/leaderboard", leaderboardHandler).Methods("GET")
@@ -1487,6 +1780,20 @@ func main() {
 	router.HandleFunc("/admin/editProblemStatement", editProblemStatement).Methods("POST", "OPTIONS")
 	router.HandleFunc("/admin/deleteProblem", deleteProblem).Methods("DELETE", "OPTIONS")
 	router.HandleFunc("/admin/uploadTestcases", uploadTestCases).Methods("POST", "OPTIONS")
+	router.HandleFunc("/admin/uploadChecker", uploadChecker).Methods("POST", "OPTIONS")
+	router.HandleFunc("/admin/uploadSubtasks", uploadSubtasks).Methods("POST", "OPTIONS")
+	router.HandleFunc("/admin/problems/{id}/validate", validateProblemHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/admin/problems/{id}/validation", getProblemValidationHandler).Methods("GET")
+	router.HandleFunc("/admin/problems/import", importProblemHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/admin/problems/{id}/export", exportProblemHandler).Methods("GET")
+	router.HandleFunc("/admin/problems/{id}/generate", generateTestCasesHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/admin/problems/{id}/testcases", getTestCasesHandler).Methods("GET")
+	router.HandleFunc("/admin/problems/{id}/testcases", createTestCaseHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/admin/problems/{id}/testcases/upload", uploadProblemTestCasesHandler).Methods("POST", "OPTIONS")
+	router.HandleFunc("/admin/problems/{id}/testcases/order", reorderTestCasesHandler).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/admin/problems/{id}/testcases/{testcaseId:[0-9]+}", getTestCaseHandler).Methods("GET")
+	router.HandleFunc("/admin/problems/{id}/testcases/{testcaseId:[0-9]+}", updateTestCaseHandler).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/admin/problems/{id}/testcases/{testcaseId:[0-9]+}", deleteTestCaseHandler).Methods("DELETE", "OPTIONS")
 	router.HandleFunc("/badges", getBadgesHandler).Methods("GET")
 	router.HandleFunc("/badges", createBadgeHandler).Methods("POST")
 	router.HandleFunc("/badges/{id}", updateBadgeHandler).Methods("PUT")
@@ -1498,6 +1805,7 @@ func main() {
 	router.HandleFunc("/admin/claims", getAllClaimsHandler).Methods("GET")
 	router.HandleFunc("/myRewards", getUserClaimsHandler).Methods("GET")
 	router.HandleFunc("/admin/stats", getAdminStats).Methods("GET")
+	router.HandleFunc("/admin/deadJobs", getDeadJobsHandler).Methods("GET")
 	router.HandleFunc("/getLeaderboardProblem", getLeaderboardProblem).Methods("GET")
 
 	log.Println("API server running on port 8080")
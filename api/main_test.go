@@ -0,0 +1,24 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Run and submit judge the stored tests only: tests sent along would come first and shift the
+// subtask indexes (a submission failing only the second stored test would score the wrong subtask)
+func TestExecuteRejectsClientTests(t *testing.T) {
+	for _, body := range []string{
+		`{"mode": "submit", "userId": "u1", "probId": "1", "language": "python", "code": "print(1)", "Inputs": ["1"], "Outputs": ["1"]}`,
+		`{"userId": "u1", "probId": "1", "language": "python", "code": "print(1)", "Inputs": ["1"]}`,
+		`{"mode": "run", "probId": "1", "language": "python", "code": "print(1)", "Outputs": ["1"]}`,
+	} {
+		w := httptest.NewRecorder()
+		executeHandler(w, httptest.NewRequest(http.MethodPost, "/execute", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "problem's tests") {
+			t.Errorf("%s: got %d %q, want 400", body, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,760 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+	"gopkg.in/yaml.v3"
+)
+
+// Problem packages: a zip with problem.yaml (see PackageManifest), the statement, the tests laid out
+// like a test case upload (tests/N.in, tests/N.out, tests/samples/, tests/subtaskN/), and the
+// checker, reference solution, generator and validator programs. Polygon packages (problem.xml)
+// can be imported too.
+const (
+	packageManifest       = "problem.yaml"
+	polygonManifest       = "problem.xml"
+	packageStatement      = "statement.md"
+	packageTestsDir       = "tests/"
+	maxPackageUploadBytes = 64 << 20
+)
+
+// PackageManifest is problem.yaml
+type PackageManifest struct {
+	Title       string           `yaml:"title"`
+	Difficulty  int              `yaml:"difficulty"`
+	Tags        []string         `yaml:"tags,omitempty"`
+	TimeLimit   int              `yaml:"timelimit"`   // seconds
+	MemoryLimit int              `yaml:"memorylimit"` // MB
+	Statement   string           `yaml:"statement"`   // markdown file, statement.md by default
+	SampleTests string           `yaml:"sampletests,omitempty"`
+	Checker     *PackageChecker  `yaml:"checker,omitempty"`
+	Function    *FunctionSpec    `yaml:"function,omitempty"` // function problems
+	Reference   *PackageProgram  `yaml:"reference,omitempty"`
+	Generator   *PackageProgram  `yaml:"generator,omitempty"`
+	Validator   *PackageProgram  `yaml:"validator,omitempty"`
+	Subtasks    []PackageSubtask `yaml:"subtasks,omitempty"`
+}
+
+type PackageChecker struct {
+	Mode       string  `yaml:"mode"`
+	AbsEpsilon float64 `yaml:"abs_epsilon,omitempty"`
+	RelEpsilon float64 `yaml:"rel_epsilon,omitempty"`
+	Language   string  `yaml:"language,omitempty"` // special and interactive modes
+	File       string  `yaml:"file,omitempty"`     // special and interactive modes
+}
+
+type PackageProgram struct {
+	Language string `yaml:"language"`
+	File     string `yaml:"file"`
+}
+
+type PackageSubtask struct {
+	Number int `yaml:"number"`
+	Points int `yaml:"points"`
+}
+
+// problemPackage is a problem as read from (or written to) a package
+type problemPackage struct {
+	Title       string
+	Difficulty  int
+	Tags        []string
+	TimeLimit   int
+	MemoryLimit int
+	Statement   string
+	SampleTests string
+	Checker     CheckerSpec
+	Function    *FunctionSpec
+	Reference   *Program
+	Generator   *Program
+	Validator   *Program
+	Subtasks    []Subtask
+	Tests       []*TestCaseFiles
+}
+
+// PackageImportReport is the answer of POST /admin/problems/import
+type PackageImportReport struct {
+	Status          string            `json:"status"`
+	Format          string            `json:"format"` // package or polygon
+	ProblemID       int               `json:"problem_id"`
+	Title           string            `json:"title"`
+	Tests           int               `json:"tests"`
+	Skipped         []SkippedTestFile `json:"skipped,omitempty"`
+	Unpaired        []string          `json:"unpaired,omitempty"`
+	ValidationJobID string            `json:"validation_job_id,omitempty"`
+}
+
+// packageFiles indexes the files of a package by path, relative to the folder of its manifest
+type packageFiles map[string]*zip.File
+
+func (files packageFiles) read(name string) (string, error) {
+	f, ok := files[path.Clean(name)]
+	if !ok {
+		return "", fmt.Errorf("%s is missing from the package", name)
+	}
+	r, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	return string(content), err
+}
+
+// program reads a program of the package, nil if p is nil
+func (files packageFiles) program(role string, p *PackageProgram) (*Program, error) {
+	if p == nil {
+		return nil, nil
+	}
+	if _, ok := languages[p.Language]; !ok {
+		return nil, fmt.Errorf("unsupported %s language %q", role, p.Language)
+	}
+	code, err := files.read(p.File)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", role, err)
+	}
+	return &Program{Language: p.Language, Code: code}, nil
+}
+
+// exportProblemHandler downloads a problem as a package
+func exportProblemHandler(w http.ResponseWriter, r *http.Request) {
+	problemID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid problem ID", http.StatusBadRequest)
+		return
+	}
+
+	pkg, err := loadProblemPackage(problemID)
+	if err == pgx.ErrNoRows {
+		http.Error(w, fmt.Sprintf("Problem with ID %d not found", problemID), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve problem: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	data, err := pkg.archive()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to build package: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="problem-%d.zip"`, problemID))
+	w.Write(data)
+}
+
+// loadProblemPackage reads everything a package holds about a problem
+func loadProblemPackage(problemID int) (*problemPackage, error) {
+	pkg := &problemPackage{}
+	var reference, generator, validator Program
+	var signature []byte
+	err := db.QueryRow(ctx, `
+		SELECT COALESCE(title, ''), COALESCE(difficulty, 0), COALESCE(tags, '{}'), COALESCE(timelimit, 0),
+			COALESCE(memorylimit, 0), question, COALESCE(tests, ''),
+			checker_mode, COALESCE(checker_abs_epsilon, 0), COALESCE(checker_rel_epsilon, 0),
+			COALESCE(checker_language, ''), COALESCE(checker_code, ''),
+			COALESCE(reference_language, ''), COALESCE(reference_code, ''),
+			COALESCE(generator_language, ''), COALESCE(generator_code, ''),
+			COALESCE(validator_language, ''), COALESCE(validator_code, ''), function_signature
+		FROM problem
+		WHERE problem_id = $1`, problemID,
+	).Scan(&pkg.Title, &pkg.Difficulty, &pkg.Tags, &pkg.TimeLimit, &pkg.MemoryLimit, &pkg.Statement, &pkg.SampleTests,
+		&pkg.Checker.Mode, &pkg.Checker.AbsEpsilon, &pkg.Checker.RelEpsilon, &pkg.Checker.Language, &pkg.Checker.Code,
+		&reference.Language, &reference.Code, &generator.Language, &generator.Code, &validator.Language, &validator.Code, &signature)
+	if err != nil {
+		return nil, err
+	}
+	if pkg.Function, err = parseFunction(signature); err != nil {
+		return nil, err
+	}
+	for _, p := range []struct {
+		program Program
+		field   **Program
+	}{{reference, &pkg.Reference}, {generator, &pkg.Generator}, {validator, &pkg.Validator}} {
+		if p.program.Code != "" {
+			program := p.program
+			*p.field = &program
+		}
+	}
+
+	pkg.Subtasks, err = problemSubtasks(strconv.Itoa(problemID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT COALESCE(tin, ''), COALESCE(tout, ''), is_sample, subtask
+		FROM testcases
+		WHERE problem_id = $1
+		ORDER BY position, testcase_id`, problemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		tc := &TestCaseFiles{}
+		if err := rows.Scan(&tc.In, &tc.Out, &tc.Sample, &tc.Subtask); err != nil {
+			return nil, err
+		}
+		pkg.Tests = append(pkg.Tests, tc)
+	}
+	return pkg, rows.Err()
+}
+
+// archive writes the package zip
+func (pkg *problemPackage) archive() ([]byte, error) {
+	manifest := PackageManifest{
+		Title:       pkg.Title,
+		Difficulty:  pkg.Difficulty,
+		Tags:        pkg.Tags,
+		TimeLimit:   pkg.TimeLimit,
+		MemoryLimit: pkg.MemoryLimit,
+		Statement:   packageStatement,
+		SampleTests: pkg.SampleTests,
+		Function:    pkg.Function,
+		Checker: &PackageChecker{
+			Mode:       pkg.Checker.Mode,
+			AbsEpsilon: pkg.Checker.AbsEpsilon,
+			RelEpsilon: pkg.Checker.RelEpsilon,
+		},
+	}
+	for _, s := range pkg.Subtasks {
+		manifest.Subtasks = append(manifest.Subtasks, PackageSubtask{Number: s.Number, Points: s.Points})
+	}
+
+	files := map[string]string{packageStatement: pkg.Statement}
+	switch pkg.Checker.Mode {
+	case CheckerSpecial, CheckerInteractive:
+		name := "checker/checker"
+		if pkg.Checker.Mode == CheckerInteractive {
+			name = "checker/interactor"
+		}
+		manifest.Checker.Language = pkg.Checker.Language
+		manifest.Checker.File = name + sourceExtension(pkg.Checker.Language)
+		files[manifest.Checker.File] = pkg.Checker.Code
+	}
+	for _, p := range []struct {
+		name    string
+		program *Program
+		field   **PackageProgram
+	}{
+		{"solutions/reference", pkg.Reference, &manifest.Reference},
+		{"generator/generator", pkg.Generator, &manifest.Generator},
+		{"validator/validator", pkg.Validator, &manifest.Validator},
+	} {
+		if p.program != nil {
+			file := p.name + sourceExtension(p.program.Language)
+			*p.field = &PackageProgram{Language: p.program.Language, File: file}
+			files[file] = p.program.Code
+		}
+	}
+
+	// Numbered per folder, in run order
+	numbers := make(map[string]int)
+	for _, tc := range pkg.Tests {
+		dir := packageTestsDir
+		if tc.Sample {
+			dir += "samples/"
+		} else if tc.Subtask != nil {
+			dir += fmt.Sprintf("subtask%d/", *tc.Subtask)
+		}
+		numbers[dir]++
+		files[fmt.Sprintf("%s%d.in", dir, numbers[dir])] = tc.In
+		files[fmt.Sprintf("%s%d.out", dir, numbers[dir])] = tc.Out
+	}
+
+	manifestData, err := yaml.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	write := func(name, content string) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(f, content)
+		return err
+	}
+	if err := write(packageManifest, string(manifestData)); err != nil {
+		return nil, err
+	}
+	for _, name := range sortedKeys(files) {
+		if err := write(name, files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// importProblemHandler creates a problem from a package (multipart "file" field)
+func importProblemHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(maxPackageUploadBytes); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid form: %v", err), http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Missing package file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read package: %v", err), http.StatusBadRequest)
+		return
+	}
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		http.Error(w, "The package is not a zip file", http.StatusBadRequest)
+		return
+	}
+
+	report := PackageImportReport{Status: "success"}
+	files, format := indexPackage(reader.File)
+	var pkg *problemPackage
+	var testReport TestCaseUploadReport
+	switch format {
+	case packageManifest:
+		report.Format = "package"
+		pkg, testReport, err = readPackage(files)
+	case polygonManifest:
+		report.Format = "polygon"
+		pkg, testReport, err = readPolygonPackage(files)
+	default:
+		http.Error(w, "The package has no problem.yaml or problem.xml", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid package: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	problemID, err := storeProblemPackage(pkg)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to import problem: %v", err), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Imported %s package as problem %d (%d tests)", report.Format, problemID, len(pkg.Tests))
+
+	report.ProblemID = problemID
+	report.Title = pkg.Title
+	report.Tests = len(pkg.Tests)
+	report.Skipped = testReport.Skipped
+	report.Unpaired = testReport.Unpaired
+	report.ValidationJobID = revalidateProblem(problemID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(report)
+}
+
+// indexPackage finds the manifest of a package (possibly inside a top folder) and indexes
+// the files under its folder. It returns the manifest name, "" if there is none.
+func indexPackage(zipFiles []*zip.File) (packageFiles, string) {
+	root, format := "", ""
+	depth := -1
+	for _, f := range zipFiles {
+		dir, name := extractFileName(f.Name)
+		if name != packageManifest && name != polygonManifest {
+			continue
+		}
+		// The shallowest manifest wins, problem.yaml over problem.xml
+		d := strings.Count(f.Name, "/")
+		if depth < 0 || d < depth || (d == depth && name == packageManifest) {
+			root, format, depth = dir, name, d
+		}
+	}
+	if format == "" {
+		return nil, ""
+	}
+
+	prefix := ""
+	if root != "" {
+		prefix = root + "/"
+	}
+	files := make(packageFiles)
+	for _, f := range zipFiles {
+		if strings.HasPrefix(f.Name, prefix) && !f.FileInfo().IsDir() {
+			files[path.Clean(strings.TrimPrefix(f.Name, prefix))] = f
+		}
+	}
+	return files, format
+}
+
+// readPackage reads a package described by problem.yaml
+func readPackage(files packageFiles) (*problemPackage, TestCaseUploadReport, error) {
+	var manifest PackageManifest
+	data, err := files.read(packageManifest)
+	if err != nil {
+		return nil, TestCaseUploadReport{}, err
+	}
+	if err := yaml.Unmarshal([]byte(data), &manifest); err != nil {
+		return nil, TestCaseUploadReport{}, fmt.Errorf("%s: %v", packageManifest, err)
+	}
+	if manifest.Title == "" {
+		return nil, TestCaseUploadReport{}, fmt.Errorf("%s has no title", packageManifest)
+	}
+
+	pkg := &problemPackage{
+		Title:       manifest.Title,
+		Difficulty:  manifest.Difficulty,
+		Tags:        manifest.Tags,
+		TimeLimit:   manifest.TimeLimit,
+		MemoryLimit: manifest.MemoryLimit,
+		SampleTests: manifest.SampleTests,
+		Checker:     CheckerSpec{Mode: CheckerExact},
+	}
+	statement := manifest.Statement
+	if statement == "" {
+		statement = packageStatement
+	}
+	if pkg.Statement, err = files.read(statement); err != nil {
+		return nil, TestCaseUploadReport{}, err
+	}
+
+	if c := manifest.Checker; c != nil && c.Mode != "" {
+		pkg.Checker = CheckerSpec{Mode: c.Mode, AbsEpsilon: c.AbsEpsilon, RelEpsilon: c.RelEpsilon}
+		if c.Mode == CheckerSpecial || c.Mode == CheckerInteractive {
+			checker, err := files.program("checker", &PackageProgram{Language: c.Language, File: c.File})
+			if err != nil {
+				return nil, TestCaseUploadReport{}, err
+			}
+			pkg.Checker.Language, pkg.Checker.Code = checker.Language, checker.Code
+		}
+	}
+	if !validCheckerMode(pkg.Checker.Mode) {
+		return nil, TestCaseUploadReport{}, fmt.Errorf("unsupported checker mode %q", pkg.Checker.Mode)
+	}
+	if err := checkFunction(manifest.Function); err != nil {
+		return nil, TestCaseUploadReport{}, fmt.Errorf("%s: %v", packageManifest, err)
+	}
+	pkg.Function = manifest.Function
+
+	if pkg.Reference, err = files.program("reference solution", manifest.Reference); err != nil {
+		return nil, TestCaseUploadReport{}, err
+	}
+	if pkg.Generator, err = files.program("generator", manifest.Generator); err != nil {
+		return nil, TestCaseUploadReport{}, err
+	}
+	if pkg.Validator, err = files.program("validator", manifest.Validator); err != nil {
+		return nil, TestCaseUploadReport{}, err
+	}
+	for _, s := range manifest.Subtasks {
+		pkg.Subtasks = append(pkg.Subtasks, Subtask{Number: s.Number, Points: s.Points})
+	}
+
+	var testFiles []*zip.File
+	for name, f := range files {
+		if strings.HasPrefix(name, packageTestsDir) {
+			testFiles = append(testFiles, f)
+		}
+	}
+	var report TestCaseUploadReport
+	pkg.Tests, report = readTestCaseFiles(testFiles)
+	return pkg, report, nil
+}
+
+// Polygon problem.xml, the parts that are imported
+type polygonProblem struct {
+	Names []struct {
+		Language string `xml:"language,attr"`
+		Value    string `xml:"value,attr"`
+	} `xml:"names>name"`
+	Testsets []struct {
+		Name          string `xml:"name,attr"`
+		TimeLimit     int    `xml:"time-limit"`   // ms
+		MemoryLimit   int64  `xml:"memory-limit"` // bytes
+		InputPattern  string `xml:"input-path-pattern"`
+		AnswerPattern string `xml:"answer-path-pattern"`
+		Tests         []struct {
+			Sample bool   `xml:"sample,attr"`
+			Group  string `xml:"group,attr"`
+		} `xml:"tests>test"`
+		Groups []struct {
+			Name   string  `xml:"name,attr"`
+			Points float64 `xml:"points,attr"`
+		} `xml:"groups>group"`
+	} `xml:"judging>testset"`
+	Checker *struct {
+		Name   string        `xml:"name,attr"`
+		Source polygonSource `xml:"source"`
+	} `xml:"assets>checker"`
+	Interactor *struct {
+		Source polygonSource `xml:"source"`
+	} `xml:"assets>interactor"`
+	Validators []struct {
+		Source polygonSource `xml:"source"`
+	} `xml:"assets>validators>validator"`
+	Solutions []struct {
+		Tag    string        `xml:"tag,attr"`
+		Source polygonSource `xml:"source"`
+	} `xml:"assets>solutions>solution"`
+}
+
+type polygonSource struct {
+	Path string `xml:"path,attr"`
+	Type string `xml:"type,attr"` // e.g. cpp.g++17, java11, python.3
+}
+
+// Polygon standard checkers that a checker mode judges exactly the same, by name. The others
+// (lcmp and fcmp compare lines, yesno and caseicmp ignore case...) are imported from their source
+// as special judges.
+var polygonCheckers = map[string]CheckerSpec{
+	"std::wcmp.cpp":  {Mode: CheckerTokens},
+	"std::ncmp.cpp":  {Mode: CheckerTokens},
+	"std::hcmp.cpp":  {Mode: CheckerTokens},
+	"std::rcmp.cpp":  {Mode: CheckerFloat, AbsEpsilon: 1.5e-6},
+	"std::rcmp4.cpp": {Mode: CheckerFloat, AbsEpsilon: 1e-4, RelEpsilon: 1e-4},
+	"std::rcmp6.cpp": {Mode: CheckerFloat, AbsEpsilon: 1e-6, RelEpsilon: 1e-6},
+	"std::rcmp9.cpp": {Mode: CheckerFloat, AbsEpsilon: 1e-9, RelEpsilon: 1e-9},
+	"std::dcmp.cpp":  {Mode: CheckerFloat, AbsEpsilon: 1e-6, RelEpsilon: 1e-6},
+}
+
+// Statement sections of a Polygon package, with the heading they get in the markdown statement
+var polygonSections = []struct{ file, heading string }{
+	{"legend.tex", ""},
+	{"input.tex", "Entrada"},
+	{"output.tex", "Salida"},
+	{"notes.tex", "Notas"},
+}
+
+// readPolygonPackage reads a Polygon package: statement sections, the main testset with its groups,
+// the checker, the first validator and the main solution. Tests without answer files (answers that
+// Polygon would generate) are reported as unpaired.
+func readPolygonPackage(files packageFiles) (*problemPackage, TestCaseUploadReport, error) {
+	var report TestCaseUploadReport
+	data, err := files.read(polygonManifest)
+	if err != nil {
+		return nil, report, err
+	}
+	var problem polygonProblem
+	if err := xml.Unmarshal([]byte(data), &problem); err != nil {
+		return nil, report, fmt.Errorf("%s: %v", polygonManifest, err)
+	}
+	if len(problem.Testsets) == 0 {
+		return nil, report, fmt.Errorf("%s has no testset", polygonManifest)
+	}
+
+	pkg := &problemPackage{Checker: CheckerSpec{Mode: CheckerTokens}}
+	language := "english"
+	for i, name := range problem.Names {
+		if i == 0 || name.Language == "spanish" {
+			pkg.Title, language = name.Value, name.Language
+		}
+	}
+	if pkg.Title == "" {
+		return nil, report, fmt.Errorf("%s has no name", polygonManifest)
+	}
+
+	// Statement: the sections of the name's language, as markdown with LaTeX
+	var statement []string
+	for _, section := range polygonSections {
+		content, err := files.read(path.Join("statement-sections", language, section.file))
+		if err != nil || strings.TrimSpace(content) == "" {
+			continue
+		}
+		if section.heading != "" {
+			content = "## " + section.heading + "\n\n" + strings.TrimSpace(content)
+		}
+		statement = append(statement, strings.TrimSpace(content))
+	}
+	pkg.Statement = strings.Join(statement, "\n\n")
+
+	testset := problem.Testsets[0]
+	for _, t := range problem.Testsets {
+		if t.Name == "tests" {
+			testset = t
+		}
+	}
+	pkg.TimeLimit = int(math.Ceil(float64(testset.TimeLimit) / 1000))
+	pkg.MemoryLimit = int(testset.MemoryLimit >> 20)
+
+	// Groups with points are subtasks, numbered by their name when it is a number
+	subtaskOf := make(map[string]int)
+	for i, g := range testset.Groups {
+		number, err := strconv.Atoi(g.Name)
+		if err != nil {
+			number = i + 1
+		}
+		subtaskOf[g.Name] = number
+		pkg.Subtasks = append(pkg.Subtasks, Subtask{Number: number, Points: int(math.Round(g.Points))})
+	}
+
+	for i, t := range testset.Tests {
+		inputPath := fmt.Sprintf(testset.InputPattern, i+1)
+		answerPath := fmt.Sprintf(testset.AnswerPattern, i+1)
+		input, inErr := files.read(inputPath)
+		answer, outErr := files.read(answerPath)
+		switch {
+		case inErr != nil && outErr != nil:
+			report.Skipped = append(report.Skipped, SkippedTestFile{File: inputPath, Reason: "generated test, not in the package"})
+			continue
+		case inErr != nil:
+			report.Unpaired = append(report.Unpaired, answerPath)
+			continue
+		case outErr != nil:
+			report.Unpaired = append(report.Unpaired, inputPath)
+			continue
+		}
+		tc := &TestCaseFiles{Number: i + 1, In: input, Out: answer, Sample: t.Sample}
+		if number, ok := subtaskOf[t.Group]; ok && !t.Sample {
+			tc.Subtask = &number
+		}
+		pkg.Tests = append(pkg.Tests, tc)
+	}
+
+	if c := problem.Checker; c != nil {
+		if spec, ok := polygonCheckers[c.Name]; ok {
+			pkg.Checker = spec
+		} else if program, err := files.polygonProgram(c.Source); err == nil {
+			pkg.Checker = CheckerSpec{Mode: CheckerSpecial, Language: program.Language, Code: program.Code}
+		} else {
+			return nil, report, fmt.Errorf("checker: %v", err)
+		}
+	}
+	// Interactive problems are judged by the interactor alone (the checker of its log is not imported)
+	if i := problem.Interactor; i != nil {
+		program, err := files.polygonProgram(i.Source)
+		if err != nil {
+			return nil, report, fmt.Errorf("interactor: %v", err)
+		}
+		pkg.Checker = CheckerSpec{Mode: CheckerInteractive, Language: program.Language, Code: program.Code}
+	}
+	if len(problem.Validators) > 0 {
+		if pkg.Validator, err = files.polygonProgram(problem.Validators[0].Source); err != nil {
+			log.Printf("Warning: skipping the validator of %s: %v", pkg.Title, err)
+		}
+	}
+	for _, s := range problem.Solutions {
+		if s.Tag == "main" {
+			if pkg.Reference, err = files.polygonProgram(s.Source); err != nil {
+				log.Printf("Warning: skipping the main solution of %s: %v", pkg.Title, err)
+			}
+		}
+	}
+	return pkg, report, nil
+}
+
+// polygonProgram reads a Polygon source file, mapping its type to a registry language
+func (files packageFiles) polygonProgram(source polygonSource) (*Program, error) {
+	language := polygonLanguage(source.Type)
+	if _, ok := languages[language]; !ok {
+		return nil, fmt.Errorf("unsupported source type %q", source.Type)
+	}
+	code, err := files.read(source.Path)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{Language: language, Code: code}, nil
+}
+
+// polygonLanguage maps a Polygon source type (cpp.g++17, java11, python.3...) to a language id
+func polygonLanguage(sourceType string) string {
+	prefixes := []struct{ prefix, language string }{
+		{"cpp.", "cpp"},
+		{"c.", "c"},
+		{"java", "java"},
+		{"python.", "python"},
+		{"pypy.", "python"},
+		{"go", "go"},
+		{"rust", "rust"},
+		{"csharp.", "csharp"},
+		{"js.", "javascript"},
+	}
+	for _, p := range prefixes {
+		if strings.HasPrefix(sourceType, p.prefix) {
+			return p.language
+		}
+	}
+	return sourceType
+}
+
+// storeProblemPackage creates the problem of a package with its subtasks and tests, in one transaction.
+// Like uploadProblemStatement, a problem with a reference solution waits for its validation.
+func storeProblemPackage(pkg *problemPackage) (int, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var reference, generator, validator Program
+	for _, p := range []struct {
+		program *Program
+		field   *Program
+	}{{pkg.Reference, &reference}, {pkg.Generator, &generator}, {pkg.Validator, &validator}} {
+		if p.program != nil {
+			*p.field = *p.program
+		}
+	}
+
+	var problemID int
+	err = tx.QueryRow(ctx, `
+		INSERT INTO problem (title, difficulty, tags, timelimit, memorylimit, question, answer, inputs, outputs, tests,
+			checker_mode, checker_abs_epsilon, checker_rel_epsilon, checker_language, checker_code,
+			reference_language, reference_code, generator_language, generator_code,
+			validator_language, validator_code, published, function_signature)
+		VALUES ($1, $2, COALESCE($3, '{}'), $4, $5, $6, ' ', '{}', '{}', $7,
+			$8, NULLIF($9, 0), NULLIF($10, 0), NULLIF($11, ''), NULLIF($12, ''),
+			NULLIF($13, ''), NULLIF($14, ''), NULLIF($15, ''), NULLIF($16, ''),
+			NULLIF($17, ''), NULLIF($18, ''), $19, $20)
+		RETURNING problem_id`,
+		pkg.Title, pkg.Difficulty, pkg.Tags, pkg.TimeLimit, pkg.MemoryLimit, pkg.Statement, pkg.SampleTests,
+		pkg.Checker.Mode, pkg.Checker.AbsEpsilon, pkg.Checker.RelEpsilon, pkg.Checker.Language, pkg.Checker.Code,
+		reference.Language, reference.Code, generator.Language, generator.Code,
+		validator.Language, validator.Code, reference.Code == "", functionJSON(pkg.Function),
+	).Scan(&problemID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, s := range pkg.Subtasks {
+		if _, err := tx.Exec(ctx, `INSERT INTO subtask (problem_id, number, points) VALUES ($1, $2, $3)`,
+			problemID, s.Number, s.Points); err != nil {
+			return 0, fmt.Errorf("subtask %d: %w", s.Number, err)
+		}
+	}
+	if err := insertTestCases(tx, problemID, 0, pkg.Tests); err != nil {
+		return 0, err
+	}
+	return problemID, tx.Commit(ctx)
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sourceExtension is the file extension of a language's source files, e.g. ".cpp"
+func sourceExtension(language string) string {
+	if lang, ok := languages[language]; ok {
+		return filepath.Ext(lang.SourceFile)
+	}
+	return ".txt"
+}
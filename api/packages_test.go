@@ -0,0 +1,85 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// polygonPackage zips a Polygon package with one test, judged by checker (built from check.cpp)
+func polygonPackage(t *testing.T, checker string) packageFiles {
+	t.Helper()
+	files := map[string]string{
+		"problem.xml": fmt.Sprintf(`<problem>
+  <names><name language="english" value="Echo"/></names>
+  <judging><testset name="tests">
+    <time-limit>1000</time-limit>
+    <memory-limit>268435456</memory-limit>
+    <input-path-pattern>tests/%%02d</input-path-pattern>
+    <answer-path-pattern>tests/%%02d.a</answer-path-pattern>
+    <tests><test/></tests>
+  </testset></judging>
+  <assets><checker name=%q type="testlib"><source path="files/check.cpp" type="cpp.g++17"/></checker></assets>
+</problem>`, checker),
+		"files/check.cpp": "// " + checker,
+		"tests/01":        "1\n",
+		"tests/01.a":      "1\n",
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexed, format := indexPackage(reader.File)
+	if format != polygonManifest {
+		t.Fatalf("package format %q, want %q", format, polygonManifest)
+	}
+	return indexed
+}
+
+// Standard checkers are mapped to a checker mode only when it judges the same; the others run
+// from their source, so that e.g. caseicmp stays case-insensitive
+func TestPolygonCheckers(t *testing.T) {
+	saved := languages
+	languages = map[string]Language{"cpp": {ID: "cpp"}}
+	t.Cleanup(func() { languages = saved })
+
+	tests := []struct {
+		checker string
+		want    CheckerSpec
+	}{
+		{"std::wcmp.cpp", CheckerSpec{Mode: CheckerTokens}},
+		{"std::rcmp6.cpp", CheckerSpec{Mode: CheckerFloat, AbsEpsilon: 1e-6, RelEpsilon: 1e-6}},
+		{"std::caseicmp.cpp", CheckerSpec{Mode: CheckerSpecial, Language: "cpp", Code: "// std::caseicmp.cpp"}},
+		{"std::fcmp.cpp", CheckerSpec{Mode: CheckerSpecial, Language: "cpp", Code: "// std::fcmp.cpp"}},
+		{"std::lcmp.cpp", CheckerSpec{Mode: CheckerSpecial, Language: "cpp", Code: "// std::lcmp.cpp"}},
+		{"std::yesno.cpp", CheckerSpec{Mode: CheckerSpecial, Language: "cpp", Code: "// std::yesno.cpp"}},
+		{"own.cpp", CheckerSpec{Mode: CheckerSpecial, Language: "cpp", Code: "// own.cpp"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.checker, func(t *testing.T) {
+			pkg, _, err := readPolygonPackage(polygonPackage(t, tt.checker))
+			if err != nil {
+				t.Fatalf("readPolygonPackage: %v", err)
+			}
+			if pkg.Checker != tt.want {
+				t.Errorf("checker %+v, want %+v", pkg.Checker, tt.want)
+			}
+			if len(pkg.Tests) != 1 {
+				t.Errorf("got %d tests, want 1", len(pkg.Tests))
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Stages of a job in the job:<id> hash, updated by the worker (see worker/status.go)
+const (
+	StageQueued  = "queued"
+	StageRunning = "running"
+)
+
+// Queue positions: job ids scored by arrival (queueSequence), removed when a worker takes them
+const (
+	queuePositions = "code_jobs:positions"
+	queueSequence  = "code_jobs:sequence"
+)
+
+// How long job:<id> is kept, same as the result
+const jobStatusTTL = 24 * time.Hour
+
+// JobProgress is what GET /result/{id} returns until the result is ready
+type JobProgress struct {
+	JobID         string `json:"job_id"`
+	Status        string `json:"status"`                   // always "pending"
+	Stage         string `json:"stage"`                    // queued, dequeued, compiling, running
+	Test          int    `json:"test,omitempty"`           // running: test being run (1-based)
+	Total         int    `json:"total,omitempty"`          // running: number of tests
+	QueuePosition int64  `json:"queue_position,omitempty"` // queued: 1 = next to be picked up
+	UpdatedAt     string `json:"updated_at,omitempty"`
+}
+
+// jobProgress reads the progress of an unfinished job, redis.Nil if the job is unknown
+func jobProgress(jobID string) (JobProgress, error) {
+	fields, err := rdb.HGetAll(ctx, "job:"+jobID).Result()
+	if err != nil {
+		return JobProgress{}, err
+	}
+	if len(fields) == 0 {
+		return JobProgress{}, redis.Nil
+	}
+
+	progress := JobProgress{
+		JobID:     jobID,
+		Status:    "pending",
+		Stage:     fields["stage"],
+		UpdatedAt: fields["updated_at"],
+	}
+	if progress.Stage == "" {
+		// Jobs queued before stages were tracked
+		progress.Stage = StageQueued
+	}
+	switch progress.Stage {
+	case StageRunning:
+		progress.Test, _ = strconv.Atoi(fields["test"])
+		progress.Total, _ = strconv.Atoi(fields["total"])
+	case StageQueued:
+		if rank, err := rdb.ZRank(ctx, queuePositions, jobID).Result(); err == nil {
+			progress.QueuePosition = rank + 1
+		}
+	}
+	return progress, nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// Event types published by the worker on job:<id>:events (see worker/events.go)
+const (
+	EventStage  = "stage"
+	EventTest   = "test"
+	EventResult = "result" // last event of a job
+)
+
+// How often an idle stream is pinged, so proxies do not close it
+const streamKeepAlive = 15 * time.Second
+
+// JobEvent is one event of a job stream. Test cases and results are forwarded as the worker sent them.
+type JobEvent struct {
+	Type          string          `json:"type"`
+	JobID         string          `json:"job_id"`
+	Stage         string          `json:"stage,omitempty"`
+	Test          int             `json:"test,omitempty"`
+	Total         int             `json:"total,omitempty"`
+	QueuePosition int64           `json:"queue_position,omitempty"`
+	Case          json.RawMessage `json:"case,omitempty"`
+	Result        json.RawMessage `json:"result,omitempty"`
+}
+
+var upgrader = websocket.Upgrader{
+	// Same policy as handleCORS: any origin
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+func eventChannel(jobID string) string {
+	return "job:" + jobID + ":events"
+}
+
+// streamHandler streams the progress, test cases and result of a job as Server-Sent Events,
+// or over a WebSocket when the request asks for an upgrade
+func streamHandler(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["id"]
+
+	sub, first, err := openJobStream(jobID)
+	if err == redis.Nil {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Error checking job status", http.StatusInternalServerError)
+		return
+	}
+	defer sub.Close()
+
+	if websocket.IsWebSocketUpgrade(r) {
+		streamWebSocket(w, r, sub, first)
+	} else {
+		streamSSE(w, r, sub, first)
+	}
+}
+
+// openJobStream subscribes to the events of a job and returns the event to send first:
+// the result if the job already finished, its current progress otherwise (redis.Nil if unknown)
+func openJobStream(jobID string) (*redis.PubSub, JobEvent, error) {
+	// Subscribe before reading the state, so no event falls in between
+	sub := rdb.Subscribe(ctx, eventChannel(jobID))
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return nil, JobEvent{}, err
+	}
+
+	resultData, err := rdb.Get(ctx, "result:"+jobID).Result()
+	if err == nil {
+		return sub, JobEvent{Type: EventResult, JobID: jobID, Result: json.RawMessage(resultData)}, nil
+	}
+	if err != redis.Nil {
+		sub.Close()
+		return nil, JobEvent{}, err
+	}
+
+	progress, err := jobProgress(jobID)
+	if err != nil {
+		sub.Close()
+		return nil, JobEvent{}, err
+	}
+	return sub, JobEvent{
+		Type:          EventStage,
+		JobID:         jobID,
+		Stage:         progress.Stage,
+		Test:          progress.Test,
+		Total:         progress.Total,
+		QueuePosition: progress.QueuePosition,
+	}, nil
+}
+
+// forwardJobEvents sends first and then every event of the job until its result, the client leaves
+// (done) or send fails. ping is called when the stream has been idle for streamKeepAlive.
+func forwardJobEvents(done <-chan struct{}, sub *redis.PubSub, first JobEvent, send func(JobEvent) error, ping func() error) {
+	// deliver sends an event and tells if the stream goes on
+	deliver := func(event JobEvent) bool {
+		if err := send(event); err != nil {
+			return false
+		}
+		return event.Type != EventResult
+	}
+	if !deliver(first) {
+		return
+	}
+
+	messages := sub.Channel()
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-keepAlive.C:
+			if err := ping(); err != nil {
+				return
+			}
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			var event JobEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				log.Printf("Error parsing event of job %s: %v", first.JobID, err)
+				continue
+			}
+			if !deliver(event) {
+				return
+			}
+		}
+	}
+}
+
+// streamSSE sends the job events as Server-Sent Events (`event: <type>`, `data: <JobEvent>`)
+func streamSSE(w http.ResponseWriter, r *http.Request, sub *redis.PubSub, first JobEvent) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // no proxy buffering (nginx)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	send := func(event JobEvent) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	ping := func() error {
+		if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	forwardJobEvents(r.Context().Done(), sub, first, send, ping)
+}
+
+// streamWebSocket sends the job events as WebSocket text messages (one JobEvent each)
+func streamWebSocket(w http.ResponseWriter, r *http.Request, sub *redis.PubSub, first JobEvent) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade failed for job %s: %v", first.JobID, err)
+		return
+	}
+	defer conn.Close()
+
+	// The client does not send anything: reading only tells us when it goes away
+	clientCtx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(event JobEvent) error {
+		return conn.WriteJSON(event)
+	}
+	ping := func() error {
+		return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamKeepAlive))
+	}
+	forwardJobEvents(clientCtx.Done(), sub, first, send, ping)
+
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+)
+
+// Page size of GET /users/{id}/submissions when pageSize is not given, and its maximum
+const (
+	defaultSubmissionsPageSize = 20
+	maxSubmissionsPageSize     = 100
+)
+
+// Submission is one row of a user's submission history
+type Submission struct {
+	SubmissionID    int       `json:"submission_id"`
+	JobID           string    `json:"job_id,omitempty"`
+	UserID          string    `json:"user_id"`
+	ProblemID       int       `json:"problem_id"`
+	ProblemTitle    string    `json:"problem_title"`
+	Date            time.Time `json:"date"`
+	Verdict         string    `json:"verdict"`
+	Correct         bool      `json:"correct"`
+	Points          int       `json:"points"`
+	Language        string    `json:"language"`
+	LanguageVersion string    `json:"language_version,omitempty"`
+	Time            float64   `json:"time"`                  // wall time of the whole job in ms
+	CPUTimeMs       *int64    `json:"cpu_time_ms,omitempty"` // CPU time of the program, unknown for older submissions
+	MemoryKB        *int64    `json:"memory_kb,omitempty"`   // peak memory of the program
+	Score           float64   `json:"score"`                 // percentage of the problem points earned (0-100)
+}
+
+// SubmissionDetail is a submission with its code and verdict breakdown
+type SubmissionDetail struct {
+	Submission
+	Output     string          `json:"output"`
+	Code       string          `json:"code,omitempty"`
+	Details    json.RawMessage `json:"details,omitempty"` // the job result: per-test results, exit code, stderr
+	CodeHidden bool            `json:"code_hidden,omitempty"`
+}
+
+type SubmissionPage struct {
+	Submissions []Submission `json:"submissions"`
+	Page        int          `json:"page"`
+	PageSize    int          `json:"page_size"`
+	Total       int          `json:"total"`
+}
+
+// Columns of Submission, in scan order
+const submissionColumns = `
+	s.submission_id, COALESCE(s.job_id::text, ''), TRIM(s.user_id), s.problem_id, COALESCE(p.title, ''), s.date,
+	COALESCE(s.verdict, CASE WHEN s.correct THEN 'AC' ELSE 'WA' END), COALESCE(s.correct, false),
+	COALESCE(s.points, 0), COALESCE(s.language, ''), COALESCE(s.language_version, ''), COALESCE(s."time", 0),
+	COALESCE(s.score, CASE WHEN s.correct THEN 100 ELSE 0 END), s.cpu_time_ms, s.memory_kb`
+
+func scanSubmission(row pgx.Row, s *Submission, extra ...interface{}) error {
+	return row.Scan(append([]interface{}{
+		&s.SubmissionID, &s.JobID, &s.UserID, &s.ProblemID, &s.ProblemTitle, &s.Date,
+		&s.Verdict, &s.Correct, &s.Points, &s.Language, &s.LanguageVersion, &s.Time, &s.Score, &s.CPUTimeMs, &s.MemoryKB,
+	}, extra...)...)
+}
+
+// getUserSubmissionsHandler lists the submissions of a user, newest first.
+// Query parameters: problemId, verdict, language, page (from 1) and pageSize.
+func getUserSubmissionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["id"]
+	query := r.URL.Query()
+
+	page, err := positiveParam(query.Get("page"), 1)
+	if err != nil {
+		http.Error(w, "Invalid page", http.StatusBadRequest)
+		return
+	}
+	pageSize, err := positiveParam(query.Get("pageSize"), defaultSubmissionsPageSize)
+	if err != nil {
+		http.Error(w, "Invalid pageSize", http.StatusBadRequest)
+		return
+	}
+	if pageSize > maxSubmissionsPageSize {
+		pageSize = maxSubmissionsPageSize
+	}
+
+	conditions := []string{"s.user_id = $1"}
+	args := []interface{}{userID}
+	addFilter := func(condition, value string) {
+		if value != "" {
+			args = append(args, value)
+			conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+		}
+	}
+	if problemID := query.Get("problemId"); problemID != "" {
+		if _, err := strconv.Atoi(problemID); err != nil {
+			http.Error(w, "Invalid problemId", http.StatusBadRequest)
+			return
+		}
+		addFilter("s.problem_id = $%d", problemID)
+	}
+	addFilter("COALESCE(s.verdict, CASE WHEN s.correct THEN 'AC' ELSE 'WA' END) = $%d", strings.ToUpper(query.Get("verdict")))
+	addFilter("s.language = $%d", query.Get("language"))
+	where := strings.Join(conditions, " AND ")
+
+	result := SubmissionPage{Submissions: []Submission{}, Page: page, PageSize: pageSize}
+	if err := db.QueryRow(ctx, `SELECT COUNT(*) FROM submission s WHERE `+where, args...).Scan(&result.Total); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to count submissions: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	args = append(args, pageSize, (page-1)*pageSize)
+	rows, err := db.Query(ctx, fmt.Sprintf(`
+		SELECT %s
+		FROM submission s
+		LEFT JOIN problem p ON p.problem_id = s.problem_id
+		WHERE %s
+		ORDER BY s.date DESC, s.submission_id DESC
+		LIMIT $%d OFFSET $%d`, submissionColumns, where, len(args)-1, len(args)), args...)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve submissions: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s Submission
+		if err := scanSubmission(rows, &s); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to scan submission: %v", err), http.StatusInternalServerError)
+			return
+		}
+		result.Submissions = append(result.Submissions, s)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, fmt.Sprintf("Error iterating through submissions: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// getSubmissionHandler returns one submission. The code and the verdict breakdown are only
+// included for its author, admins and users who already solved the problem (?userId= is the viewer).
+func getSubmissionHandler(w http.ResponseWriter, r *http.Request) {
+	submissionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid submission ID", http.StatusBadRequest)
+		return
+	}
+	viewerID := r.URL.Query().Get("userId")
+
+	var s SubmissionDetail
+	var details []byte
+	err = scanSubmission(db.QueryRow(ctx, `
+		SELECT `+submissionColumns+`, COALESCE(s.submission_result, ''), COALESCE(s.code, ''), s.details
+		FROM submission s
+		LEFT JOIN problem p ON p.problem_id = s.problem_id
+		WHERE s.submission_id = $1`, submissionID), &s.Submission, &s.Output, &s.Code, &details)
+	if err == pgx.ErrNoRows {
+		http.Error(w, "Submission not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve submission: %v", err), http.StatusInternalServerError)
+		return
+	}
+	s.Details = details
+
+	canSee, err := canSeeSubmissionCode(viewerID, s.Submission)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to check access: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !canSee {
+		s.Code, s.Details, s.Output = "", nil, ""
+		s.CodeHidden = true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
+
+// canSeeSubmissionCode tells if viewer may read the code of s: its author, an admin,
+// or someone who already solved the problem
+func canSeeSubmissionCode(viewerID string, s Submission) (bool, error) {
+	if viewerID == "" {
+		return false, nil
+	}
+	if viewerID == s.UserID {
+		return true, nil
+	}
+	var allowed bool
+	err := db.QueryRow(ctx, `
+		SELECT COALESCE((SELECT is_admin FROM "User" WHERE user_id = $1), false)
+			OR EXISTS (SELECT 1 FROM submission WHERE user_id = $1 AND problem_id = $2 AND correct = true)`,
+		viewerID, s.ProblemID).Scan(&allowed)
+	return allowed, err
+}
+
+// positiveParam parses an optional positive integer query parameter
+func positiveParam(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	return n, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Subtask groups test cases of a problem, IOI-style: its points are earned only if all its tests pass
+type Subtask struct {
+	Number int   `json:"number"`
+	Points int   `json:"points"`
+	Tests  []int `json:"tests,omitempty"` // 0-based indexes in Job.Inputs
+}
+
+// SubtaskResult is the score of one subtask, as computed by the worker
+type SubtaskResult struct {
+	Number  int    `json:"number"`
+	Points  int    `json:"points"` // Earned: all or nothing
+	Max     int    `json:"max"`
+	Verdict string `json:"verdict,omitempty"` // First failing verdict, AC if every test passed
+	Passed  int    `json:"passed"`
+	Total   int    `json:"total"`
+}
+
+type SubtaskFormat struct {
+	ProblemID int       `json:"problem_id"`
+	Subtasks  []Subtask `json:"subtasks"`
+}
+
+// Test cases of subtask N are uploaded in a subtaskN/ folder of the zip
+var subtaskDirRegex = regexp.MustCompile(`^subtask(\d+)$`)
+
+// subtaskOfDir returns the subtask of the test cases in a zip directory, nil if none
+func subtaskOfDir(dir string) *int {
+	for _, part := range strings.Split(dir, "/") {
+		if m := subtaskDirRegex.FindStringSubmatch(strings.ToLower(part)); m != nil {
+			number, err := strconv.Atoi(m[1])
+			if err == nil {
+				return &number
+			}
+		}
+	}
+	return nil
+}
+
+// problemSubtasks loads the subtasks of a problem and assigns them their tests,
+// given the subtask of each test (nil for tests outside any subtask)
+func problemSubtasks(problemID string, testSubtasks []*int) ([]Subtask, error) {
+	rows, err := db.Query(ctx, `SELECT number, points FROM subtask WHERE problem_id = $1 ORDER BY number`, problemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subtasks []Subtask
+	for rows.Next() {
+		var s Subtask
+		if err := rows.Scan(&s.Number, &s.Points); err != nil {
+			return nil, err
+		}
+		for i, number := range testSubtasks {
+			if number != nil && *number == s.Number {
+				s.Tests = append(s.Tests, i)
+			}
+		}
+		subtasks = append(subtasks, s)
+	}
+	return subtasks, rows.Err()
+}
+
+// uploadSubtasks replaces the subtasks (number and points) of a problem.
+// An empty list makes the problem all or nothing again.
+func uploadSubtasks(w http.ResponseWriter, r *http.Request) {
+	var format SubtaskFormat
+	if err := json.NewDecoder(r.Body).Decode(&format); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	seen := make(map[int]bool)
+	for _, s := range format.Subtasks {
+		if s.Points < 0 {
+			http.Error(w, "Subtask points cannot be negative", http.StatusBadRequest)
+			return
+		}
+		if seen[s.Number] {
+			http.Error(w, fmt.Sprintf("Subtask %d appears twice", s.Number), http.StatusBadRequest)
+			return
+		}
+		seen[s.Number] = true
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update subtasks: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM problem WHERE problem_id = $1)`, format.ProblemID).Scan(&exists); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update subtasks: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, fmt.Sprintf("Problem with ID %d not found", format.ProblemID), http.StatusNotFound)
+		return
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM subtask WHERE problem_id = $1`, format.ProblemID); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update subtasks: %v", err), http.StatusInternalServerError)
+		return
+	}
+	for _, s := range format.Subtasks {
+		if _, err := tx.Exec(ctx, `INSERT INTO subtask (problem_id, number, points) VALUES ($1, $2, $3)`,
+			format.ProblemID, s.Number, s.Points); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to update subtasks: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update subtasks: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
+}
@@ -0,0 +1,573 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+)
+
+// Characters of the input / output of each test case GET /admin/problems/{id}/testcases returns
+const testCasePreviewChars = 256
+
+// TestCase is a stored test case; the list endpoint only returns previews of the input and output
+type TestCase struct {
+	TestCaseID int     `json:"testcase_id"`
+	Position   int     `json:"position"` // order in which the tests run
+	Sample     bool    `json:"sample"`
+	Subtask    *int    `json:"subtask,omitempty"`
+	Generator  *string `json:"generator_args,omitempty"` // arguments of the generator, for generated tests
+	Input      string  `json:"input"`
+	Output     string  `json:"output"`
+	InputSize  int     `json:"input_size"`
+	OutputSize int     `json:"output_size"`
+}
+
+// TestCaseUploadReport tells what an upload stored and which files it left out
+type TestCaseUploadReport struct {
+	Message  string            `json:"message"`
+	Mode     string            `json:"mode"` // append or replace
+	Inserted int               `json:"inserted"`
+	Deleted  int64             `json:"deleted,omitempty"`  // replace mode: previous test cases
+	Skipped  []SkippedTestFile `json:"skipped,omitempty"`  // files that are not test cases
+	Unpaired []string          `json:"unpaired,omitempty"` // .in without .out, or the other way around
+
+	ValidationJobID string `json:"validation_job_id,omitempty"` // run of the reference solution, if any
+}
+
+type SkippedTestFile struct {
+	File   string `json:"file"`
+	Reason string `json:"reason"`
+}
+
+// Upload modes: add the test cases after the existing ones, or replace them all
+const (
+	UploadAppend  = "append"
+	UploadReplace = "replace"
+)
+
+var testCaseFileRegex = regexp.MustCompile(`^(\d+)\.(in|out)$`)
+
+// readTestCaseZip pairs the N.in / N.out files of a zip, in a deterministic order:
+// samples first, then the tests outside subtasks, then each subtask, each by number.
+func readTestCaseZip(data []byte) ([]*TestCaseFiles, TestCaseUploadReport, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, TestCaseUploadReport{}, err
+	}
+	testCases, report := readTestCaseFiles(reader.File)
+	return testCases, report, nil
+}
+
+// readTestCaseFiles pairs the N.in / N.out files among files of a zip, see readTestCaseZip
+func readTestCaseFiles(zipFiles []*zip.File) ([]*TestCaseFiles, TestCaseUploadReport) {
+	var report TestCaseUploadReport
+	testCases := make(map[string]*TestCaseFiles)
+	for _, zipFile := range zipFiles {
+		if zipFile.FileInfo().IsDir() {
+			continue
+		}
+		dir, fileName := extractFileName(zipFile.Name)
+
+		match := testCaseFileRegex.FindStringSubmatch(fileName)
+		if match == nil {
+			report.Skipped = append(report.Skipped, SkippedTestFile{File: zipFile.Name, Reason: "name is not N.in or N.out"})
+			continue
+		}
+		number, _ := strconv.Atoi(match[1])
+		ext := match[2]
+
+		files := &TestCaseFiles{Number: number, Sample: isSampleDir(dir)}
+		if !files.Sample {
+			files.Subtask = subtaskOfDir(dir)
+		}
+		key := files.name()
+		if existing, ok := testCases[key]; ok {
+			files = existing
+		} else {
+			testCases[key] = files
+		}
+
+		if (ext == "in" && files.hasIn) || (ext == "out" && files.hasOut) {
+			report.Skipped = append(report.Skipped, SkippedTestFile{File: zipFile.Name, Reason: "duplicate of " + key + "." + ext})
+			continue
+		}
+
+		zippedFile, err := zipFile.Open()
+		if err != nil {
+			report.Skipped = append(report.Skipped, SkippedTestFile{File: zipFile.Name, Reason: err.Error()})
+			continue
+		}
+		content, err := io.ReadAll(zippedFile)
+		zippedFile.Close()
+		if err != nil {
+			report.Skipped = append(report.Skipped, SkippedTestFile{File: zipFile.Name, Reason: err.Error()})
+			continue
+		}
+
+		if ext == "in" {
+			files.In, files.hasIn = string(content), true
+		} else {
+			files.Out, files.hasOut = string(content), true
+		}
+	}
+
+	var paired []*TestCaseFiles
+	for key, files := range testCases {
+		switch {
+		case !files.hasIn:
+			report.Unpaired = append(report.Unpaired, key+".out")
+		case !files.hasOut:
+			report.Unpaired = append(report.Unpaired, key+".in")
+		default:
+			paired = append(paired, files)
+		}
+	}
+	sort.Strings(report.Unpaired)
+	sort.Slice(paired, func(i, j int) bool {
+		if gi, gj := paired[i].group(), paired[j].group(); gi != gj {
+			return gi < gj
+		}
+		return paired[i].Number < paired[j].Number
+	})
+	return paired, report
+}
+
+// name identifies the test case in upload reports, e.g. samples/1 or subtask2/10
+func (f *TestCaseFiles) name() string {
+	switch {
+	case f.Sample:
+		return fmt.Sprintf("samples/%d", f.Number)
+	case f.Subtask != nil:
+		return fmt.Sprintf("subtask%d/%d", *f.Subtask, f.Number)
+	}
+	return strconv.Itoa(f.Number)
+}
+
+// group orders the test cases of an upload: samples, tests outside subtasks, then subtasks by number
+func (f *TestCaseFiles) group() int {
+	switch {
+	case f.Sample:
+		return -2
+	case f.Subtask != nil:
+		return *f.Subtask
+	}
+	return -1
+}
+
+// storeTestCases saves the test cases of an upload in a single transaction, after the
+// existing ones or instead of them (UploadReplace)
+func storeTestCases(problemID int, mode string, testCases []*TestCaseFiles, report *TestCaseUploadReport) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	position := 0
+	if mode == UploadReplace {
+		tag, err := tx.Exec(ctx, `DELETE FROM testcases WHERE problem_id = $1`, problemID)
+		if err != nil {
+			return err
+		}
+		report.Deleted = tag.RowsAffected()
+	} else if err := tx.QueryRow(ctx, `SELECT COALESCE(MAX(position), 0) FROM testcases WHERE problem_id = $1`, problemID).Scan(&position); err != nil {
+		return err
+	}
+
+	if err := insertTestCases(tx, problemID, position, testCases); err != nil {
+		return err
+	}
+	report.Inserted = len(testCases)
+	return tx.Commit(ctx)
+}
+
+// insertTestCases adds test cases to a problem, in order after position
+func insertTestCases(tx pgx.Tx, problemID, position int, testCases []*TestCaseFiles) error {
+	for _, files := range testCases {
+		position++
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO testcases (problem_id, tin, tout, is_sample, subtask, position)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			problemID, files.In, files.Out, files.Sample, files.Subtask, position); err != nil {
+			return fmt.Errorf("test case %s: %w", files.name(), err)
+		}
+	}
+	return nil
+}
+
+// problemTestCases reads the test cases of a problem in run order (only the samples unless all is set),
+// with the subtask of each one. It fails if any test case cannot be read.
+func problemTestCases(problemID string, all bool) ([]string, []string, []*int, error) {
+	rows, err := db.Query(ctx, `
+		SELECT t.tin, t.tout, t.subtask
+		FROM testcases t
+		JOIN problem p ON p.problem_id = t.problem_id
+		WHERE p.problem_id = $1 AND (t.is_sample OR $2)
+		ORDER BY t.position, t.testcase_id;
+	`, problemID, all)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer rows.Close()
+
+	var inputs, outputs []string
+	var subtasks []*int
+	for rows.Next() {
+		var input, output string
+		var subtask *int
+		if err := rows.Scan(&input, &output, &subtask); err != nil {
+			// Judging without this test would shift the subtask indexes and accept on fewer tests
+			return nil, nil, nil, fmt.Errorf("reading test case: %w", err)
+		}
+		inputs = append(inputs, input)
+		outputs = append(outputs, output)
+		subtasks = append(subtasks, subtask)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+	return inputs, outputs, subtasks, nil
+}
+
+// problemExists tells if a problem id is known, answering 400/404/500 otherwise
+func problemExists(w http.ResponseWriter, id string) (int, bool) {
+	problemID, err := strconv.Atoi(id)
+	if err != nil {
+		http.Error(w, "Invalid problem ID", http.StatusBadRequest)
+		return 0, false
+	}
+	var exists bool
+	if err := db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM problem WHERE problem_id = $1)`, problemID).Scan(&exists); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve problem: %v", err), http.StatusInternalServerError)
+		return 0, false
+	}
+	if !exists {
+		http.Error(w, fmt.Sprintf("Problem with ID %d not found", problemID), http.StatusNotFound)
+		return 0, false
+	}
+	return problemID, true
+}
+
+// getTestCasesHandler lists the test cases of a problem in run order, with previews of their input and output
+func getTestCasesHandler(w http.ResponseWriter, r *http.Request) {
+	problemID, ok := problemExists(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT testcase_id, COALESCE(position, 0), is_sample, subtask, generator_args,
+			LEFT(COALESCE(tin, ''), $2), LEFT(COALESCE(tout, ''), $2),
+			OCTET_LENGTH(COALESCE(tin, '')), OCTET_LENGTH(COALESCE(tout, ''))
+		FROM testcases
+		WHERE problem_id = $1
+		ORDER BY position, testcase_id`, problemID, testCasePreviewChars)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve test cases: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	testCases := []TestCase{}
+	for rows.Next() {
+		var tc TestCase
+		if err := rows.Scan(&tc.TestCaseID, &tc.Position, &tc.Sample, &tc.Subtask, &tc.Generator,
+			&tc.Input, &tc.Output, &tc.InputSize, &tc.OutputSize); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to scan test case: %v", err), http.StatusInternalServerError)
+			return
+		}
+		testCases = append(testCases, tc)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, fmt.Sprintf("Error iterating through test cases: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(testCases)
+}
+
+// testCaseIDs reads the problem and test case ids of a test case route, answering 400 if one is invalid
+func testCaseIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	vars := mux.Vars(r)
+	problemID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid problem ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	testCaseID, err := strconv.Atoi(vars["testcaseId"])
+	if err != nil {
+		http.Error(w, "Invalid test case ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return problemID, testCaseID, true
+}
+
+// getTestCaseHandler returns one test case with its full input and output
+func getTestCaseHandler(w http.ResponseWriter, r *http.Request) {
+	problemID, testCaseID, ok := testCaseIDs(w, r)
+	if !ok {
+		return
+	}
+
+	var tc TestCase
+	err := db.QueryRow(ctx, `
+		SELECT testcase_id, COALESCE(position, 0), is_sample, subtask, generator_args, COALESCE(tin, ''), COALESCE(tout, '')
+		FROM testcases
+		WHERE problem_id = $1 AND testcase_id = $2`, problemID, testCaseID,
+	).Scan(&tc.TestCaseID, &tc.Position, &tc.Sample, &tc.Subtask, &tc.Generator, &tc.Input, &tc.Output)
+	if err == pgx.ErrNoRows {
+		http.Error(w, "Test case not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve test case: %v", err), http.StatusInternalServerError)
+		return
+	}
+	tc.InputSize, tc.OutputSize = len(tc.Input), len(tc.Output)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tc)
+}
+
+// createTestCaseHandler adds one test case after the existing ones
+func createTestCaseHandler(w http.ResponseWriter, r *http.Request) {
+	problemID, ok := problemExists(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	var tc TestCase
+	if err := json.NewDecoder(r.Body).Decode(&tc); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	err := db.QueryRow(ctx, `
+		INSERT INTO testcases (problem_id, tin, tout, is_sample, subtask, position)
+		VALUES ($1, $2, $3, $4, $5, (SELECT COALESCE(MAX(position), 0) + 1 FROM testcases WHERE problem_id = $1))
+		RETURNING testcase_id, position`,
+		problemID, tc.Input, tc.Output, tc.Sample, tc.Subtask,
+	).Scan(&tc.TestCaseID, &tc.Position)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to insert test case: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	revalidateProblem(problemID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":      "created",
+		"testcase_id": tc.TestCaseID,
+		"position":    tc.Position,
+	})
+}
+
+// updateTestCaseHandler replaces the input, output, sample flag and subtask of a test case
+func updateTestCaseHandler(w http.ResponseWriter, r *http.Request) {
+	problemID, testCaseID, ok := testCaseIDs(w, r)
+	if !ok {
+		return
+	}
+
+	var tc TestCase
+	if err := json.NewDecoder(r.Body).Decode(&tc); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	tag, err := db.Exec(ctx, `
+		UPDATE testcases
+		SET tin = $1, tout = $2, is_sample = $3, subtask = $4
+		WHERE problem_id = $5 AND testcase_id = $6`,
+		tc.Input, tc.Output, tc.Sample, tc.Subtask, problemID, testCaseID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to update test case: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		http.Error(w, "Test case not found", http.StatusNotFound)
+		return
+	}
+	revalidateProblem(problemID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
+}
+
+// deleteTestCaseHandler removes one test case
+func deleteTestCaseHandler(w http.ResponseWriter, r *http.Request) {
+	problemID, testCaseID, ok := testCaseIDs(w, r)
+	if !ok {
+		return
+	}
+
+	tag, err := db.Exec(ctx, `DELETE FROM testcases WHERE problem_id = $1 AND testcase_id = $2`, problemID, testCaseID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete test case: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		http.Error(w, "Test case not found", http.StatusNotFound)
+		return
+	}
+	revalidateProblem(problemID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
+
+// reorderTestCasesHandler sets the run order of the test cases of a problem.
+// The body lists every test case id of the problem, in the new order: {"order": [3, 1, 2]}
+func reorderTestCasesHandler(w http.ResponseWriter, r *http.Request) {
+	problemID, ok := problemExists(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Order []int `json:"order"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to reorder test cases: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the test cases, so the order is checked against what gets updated
+	rows, err := tx.Query(ctx, `SELECT testcase_id FROM testcases WHERE problem_id = $1 FOR UPDATE`, problemID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to reorder test cases: %v", err), http.StatusInternalServerError)
+		return
+	}
+	current := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			http.Error(w, fmt.Sprintf("Failed to reorder test cases: %v", err), http.StatusInternalServerError)
+			return
+		}
+		current[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to reorder test cases: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if len(req.Order) != len(current) {
+		http.Error(w, fmt.Sprintf("order must list all %d test cases of the problem", len(current)), http.StatusBadRequest)
+		return
+	}
+	for i, id := range req.Order {
+		if !current[id] {
+			http.Error(w, fmt.Sprintf("Test case %d is not a test case of the problem, or appears twice", id), http.StatusBadRequest)
+			return
+		}
+		delete(current, id)
+		if _, err := tx.Exec(ctx, `UPDATE testcases SET position = $1 WHERE testcase_id = $2`, i+1, id); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to reorder test cases: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to reorder test cases: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
+}
+
+// uploadProblemTestCasesHandler stores the test cases of a zip (see uploadTestCases), ?mode=append or replace
+func uploadProblemTestCasesHandler(w http.ResponseWriter, r *http.Request) {
+	problemID, ok := problemExists(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	importTestCaseZip(w, r, problemID)
+}
+
+// importTestCaseZip reads the zip of the multipart "file" field and stores its test cases,
+// answering with the upload report
+func importTestCaseZip(w http.ResponseWriter, r *http.Request, problemID int) {
+	w.Header().Set("Content-Type", "application/json")
+
+	respondWithError := func(status int, message string) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": message})
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = UploadAppend
+	}
+	if mode != UploadAppend && mode != UploadReplace {
+		respondWithError(http.StatusBadRequest, "Unsupported mode. Supported modes: append, replace")
+		return
+	}
+
+	if err := r.ParseMultipartForm(10 << 20); err != nil { // 10 MB
+		fmt.Println("Error parsing form:", err)
+		respondWithError(http.StatusBadRequest, "No se pudo parsear el formulario")
+		return
+	}
+
+	file, handler, err := r.FormFile("file")
+	if err != nil {
+		fmt.Println("Error retrieving the file:", err)
+		respondWithError(http.StatusBadRequest, "Error al obtener el archivo")
+		return
+	}
+	defer file.Close()
+
+	fmt.Println("Uploaded File:", handler.Filename)
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		fmt.Println("Error copying file to buffer:", err)
+		respondWithError(http.StatusInternalServerError, "Error leyendo el archivo")
+		return
+	}
+
+	testCases, report, err := readTestCaseZip(data)
+	if err != nil {
+		fmt.Println("Error opening zip:", err)
+		respondWithError(http.StatusBadRequest, "Error abriendo el archivo zip")
+		return
+	}
+	if mode == UploadReplace && len(testCases) == 0 {
+		respondWithError(http.StatusBadRequest, "El zip no tiene test cases: no se reemplazan los existentes")
+		return
+	}
+
+	report.Mode = mode
+	if err := storeTestCases(problemID, mode, testCases, &report); err != nil {
+		fmt.Println("Error inserting test case into database:", err)
+		respondWithError(http.StatusInternalServerError, "Error inserting test case into database")
+		return
+	}
+
+	report.Message = "Test cases procesados exitosamente"
+	report.ValidationJobID = revalidateProblem(problemID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
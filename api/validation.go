@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+)
+
+// Validation status of a problem with a reference solution; only valid problems are published
+const (
+	ValidationPending = "pending" // waiting for the worker, or for test cases
+	ValidationValid   = "valid"
+	ValidationInvalid = "invalid"
+)
+
+// ProblemValidation is what GET /admin/problems/{id}/validation returns
+type ProblemValidation struct {
+	ProblemID          int    `json:"problem_id"`
+	Published          bool   `json:"published"`
+	ReferenceLanguage  string `json:"reference_language,omitempty"`
+	Status             string `json:"status,omitempty"` // pending, valid or invalid; empty without reference solution
+	Message            string `json:"message,omitempty"`
+	JobID              string `json:"job_id,omitempty"`              // follow it with /result/{id}/stream
+	ReferenceTimeMs    *int   `json:"reference_time_ms,omitempty"`   // slowest test of the reference solution
+	SuggestedTimeLimit *int   `json:"suggested_timelimit,omitempty"` // in seconds, like problem.timelimit
+	TimeLimit          int    `json:"timelimit"`
+}
+
+// validateProblem runs the reference solution of a problem on all its test cases, with the problem's
+// limits and checker. The problem is unpublished until the worker reports that every test passed.
+// It returns the validation job id, "" if the problem has no reference solution or no test cases.
+func validateProblem(problemID int) (string, error) {
+	id := strconv.Itoa(problemID)
+
+	var language, code string
+	err := db.QueryRow(ctx, `
+		SELECT COALESCE(reference_language, ''), COALESCE(reference_code, '')
+		FROM problem
+		WHERE problem_id = $1`, problemID).Scan(&language, &code)
+	if err != nil {
+		return "", err
+	}
+	if language == "" || code == "" {
+		return "", nil
+	}
+
+	inputs, outputs, _, err := problemTestCases(id, true)
+	if err != nil {
+		return "", err
+	}
+	if len(inputs) == 0 {
+		_, err := db.Exec(ctx, `
+			UPDATE problem
+			SET published = false, validation_status = $1, validation_message = $2, validation_job_id = NULL
+			WHERE problem_id = $3`,
+			ValidationPending, "The problem has no test cases yet", problemID)
+		return "", err
+	}
+
+	timeLimit, memoryLimit, checker, err := problemJudging(id)
+	if err != nil {
+		return "", err
+	}
+	function, err := problemFunction(id)
+	if err != nil {
+		return "", err
+	}
+
+	job := Job{
+		ID:          uuid.NewString(),
+		Language:    language,
+		Code:        code,
+		Timestamp:   time.Now(),
+		Inputs:      inputs,
+		Outputs:     outputs,
+		ProblemID:   id,
+		TimeLimit:   timeLimit,
+		MemoryLimit: memoryLimit,
+		Checker:     checker,
+		Function:    function,
+		RunAll:      true,
+		Mode:        ModeValidate,
+	}
+
+	// The job id goes in first: the worker only records the outcome of the latest validation
+	_, err = db.Exec(ctx, `
+		UPDATE problem
+		SET published = false, validation_status = $1, validation_message = NULL, validation_job_id = $2
+		WHERE problem_id = $3`,
+		ValidationPending, job.ID, problemID)
+	if err != nil {
+		return "", err
+	}
+	if err := enqueueJob(job); err != nil {
+		return "", err
+	}
+	log.Printf("Validating problem %d with its %s reference solution (job %s)", problemID, language, job.ID)
+	return job.ID, nil
+}
+
+// revalidateProblem starts a new validation after a change of the problem or its test cases, logging failures
+func revalidateProblem(problemID int) string {
+	jobID, err := validateProblem(problemID)
+	if err != nil {
+		log.Printf("Warning: failed to validate problem %d: %v", problemID, err)
+	}
+	return jobID
+}
+
+// validReference checks the reference solution of an upload, answering 400 if it is incomplete
+func validReference(w http.ResponseWriter, language, code string) bool {
+	if (language == "") != (code == "") {
+		http.Error(w, "A reference solution needs its language and code", http.StatusBadRequest)
+		return false
+	}
+	if _, ok := languages[language]; language != "" && !ok {
+		http.Error(w, "Unsupported reference language. Supported languages: "+supportedLanguages(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// validateProblemHandler runs the reference solution of a problem again
+func validateProblemHandler(w http.ResponseWriter, r *http.Request) {
+	problemID, ok := problemExists(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	jobID, err := validateProblem(problemID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to validate problem: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if jobID == "" {
+		http.Error(w, "The problem has no reference solution or no test cases", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": ValidationPending, "job_id": jobID})
+}
+
+// getProblemValidationHandler tells if a problem passed the validation with its reference solution
+func getProblemValidationHandler(w http.ResponseWriter, r *http.Request) {
+	problemID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid problem ID", http.StatusBadRequest)
+		return
+	}
+
+	v := ProblemValidation{ProblemID: problemID}
+	err = db.QueryRow(ctx, `
+		SELECT published, COALESCE(reference_language, ''), COALESCE(validation_status, ''),
+			COALESCE(validation_message, ''), COALESCE(validation_job_id::text, ''),
+			reference_time_ms, suggested_timelimit, COALESCE(timelimit, 0)
+		FROM problem
+		WHERE problem_id = $1`, problemID,
+	).Scan(&v.Published, &v.ReferenceLanguage, &v.Status, &v.Message, &v.JobID,
+		&v.ReferenceTimeMs, &v.SuggestedTimeLimit, &v.TimeLimit)
+	if err == pgx.ErrNoRows {
+		http.Error(w, fmt.Sprintf("Problem with ID %d not found", problemID), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve problem: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
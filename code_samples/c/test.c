@@ -0,0 +1,5 @@
+#include <stdio.h>
+int main() {
+    printf("Hello from C\n");
+    return 0;
+}
@@ -0,0 +1,78 @@
+#!/bin/bash
+
+# Phases (PHASE env):
+#   compile  compile the code copied in by the worker (exit 1 on compilation errors)
+#   run      run the compiled program with the piped stdin (and $ARGS as arguments)
+#   (unset)  both, one after the other
+
+# Limits passed by the worker (wall seconds, CPU seconds)
+TIMEOUT="${TIMEOUT:-5}"
+CPU_LIMIT="${CPU_LIMIT:-5}"
+
+# How to build and run the code, from the language registry (languages.json)
+SOURCE_FILE="${SOURCE_FILE:-main.c}"
+COMPILE_CMD="${COMPILE_CMD-gcc -std=c11 -O2 -o program main.c -lm}"
+RUN_CMD="${RUN_CMD:-./program}"
+
+# Work directory, where the worker copies the code and the run phase finds what the compile phase built
+# (set by the worker for a second program in the same container)
+WORK_DIR="${WORK_DIR:-/tmp/work}"
+
+compile() {
+    # The worker copies the code into the work directory before compiling
+    if [ ! -f "$WORK_DIR/$SOURCE_FILE" ]; then
+        echo "Error: $SOURCE_FILE not found in $WORK_DIR" >&2
+        exit 2
+    fi
+    cd "$WORK_DIR" || exit 2
+
+    # Compile the code
+    if [ -n "$COMPILE_CMD" ] && ! sh -c "$COMPILE_CMD" > compile_error 2>&1; then
+        echo "Compilation error:" >&2
+        cat compile_error >&2
+        exit 1
+    fi
+}
+
+# Run the compiled program under the CPU and wall time limits, measured by GNU time
+# (hard CPU limit one second later so the program gets SIGXCPU rather than SIGKILL).
+# GNU time writes the usage to its stderr, captured here, while the program gets the real stdout and
+# stderr (fds 3 and 4) and no other descriptor: unlike a file in the work directory, it cannot forge
+# the report. RUN_CMD is a shell command; $ARGS are only split into its arguments, never
+# evaluated nor globbed. Sets EXIT_CODE and USAGE.
+run_program() {
+    cd "$WORK_DIR" || exit 2
+    exec 3>&1 4>&2
+    REPORT=$( (set -f; ulimit -S -t "$CPU_LIMIT"; ulimit -H -t $((CPU_LIMIT + 1)); exec /usr/bin/time -q -f "%U %S %M" timeout "${TIMEOUT}s" sh -c "exec 2>&4 3>&- 4>&- 5>&- 6>&- 7>&- 8>&- 9>&-; exec $RUN_CMD \"\$@\"" sh $ARGS) 2>&1 1>&3; echo "$?")
+    exec 3>&- 4>&-
+    EXIT_CODE=$(printf '%s\n' "$REPORT" | tail -n 1)
+    USAGE=$(printf '%s\n' "$REPORT" | sed '$d' | tail -n 1)
+}
+
+# Report the CPU time (user and system seconds) and peak memory (KB) of the program to the worker
+# on the last line of stderr, nothing if GNU time did not write them (the worker takes them as unknown)
+report_usage() {
+    if [ -n "$USAGE" ]; then
+        echo "EXECUTOR_USAGE $USAGE" >&2
+    fi
+}
+
+run() {
+    # Single runs get no input, test runs read the piped stdin (docker exec -i)
+    run_program
+
+    # Check if execution timed out
+    if [ $EXIT_CODE -eq 124 ]; then
+        echo "Execution timed out." >&2
+    fi
+    report_usage
+
+    # Exit with the same code as the program
+    exit $EXIT_CODE
+}
+
+case "$PHASE" in
+    compile) compile ;;
+    run) run ;;
+    *) compile; run ;;
+esac
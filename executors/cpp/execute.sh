@@ -1,63 +1,78 @@
 #!/bin/bash
 
-# Check if CODE_URL is provided
-if [ -z "$CODE_URL" ]; then
-    echo "Error: CODE_URL environment variable not set." >&2
-    exit 1
-fi
-
-# Create a temporary directory
-TEMP_DIR=$(mktemp -d)
-CODE_FILE="${TEMP_DIR}/code.cpp"
-
-# Download the code using curl
-curl -s "$CODE_URL" > "$CODE_FILE"
-
-# Check if download was successful
-if [ $? -ne 0 ] || [ ! -s "$CODE_FILE" ]; then
-    echo "Error: Failed to download code from $CODE_URL" >&2
-    rm -rf "$TEMP_DIR"
-    exit 1
-fi
-
-# Compile the code
-g++ -std=c++17 -o "${TEMP_DIR}/program" "$CODE_FILE" 2>"${TEMP_DIR}/compile_error"
-
-# Check if compilation was successful
-if [ $? -ne 0 ]; then
-    echo "Compilation error:" >&2
-    cat "${TEMP_DIR}/compile_error" >&2
-    rm -rf "$TEMP_DIR"
-    exit 1
-fi
-
-# Check if this is a single run or test run
-if [ -n "$SINGLE" ]; then
-    # Single run: check if stdin has data available
-    if [ -t 0 ]; then
-        # No input available, run without input
-        timeout 5s "${TEMP_DIR}/program"
-    else
-        # Input available, pipe it
-        timeout 5s "${TEMP_DIR}/program"
+# Phases (PHASE env):
+#   compile  compile the code copied in by the worker (exit 1 on compilation errors)
+#   run      run the compiled program with the piped stdin (and $ARGS as arguments)
+#   (unset)  both, one after the other
+
+# Limits passed by the worker (wall seconds, CPU seconds)
+TIMEOUT="${TIMEOUT:-5}"
+CPU_LIMIT="${CPU_LIMIT:-5}"
+
+# How to build and run the code, from the language registry (languages.json)
+SOURCE_FILE="${SOURCE_FILE:-code.cpp}"
+COMPILE_CMD="${COMPILE_CMD-g++ -std=c++17 -O2 -o program code.cpp}"
+RUN_CMD="${RUN_CMD:-./program}"
+
+# Work directory, where the worker copies the code and the run phase finds what the compile phase built
+# (set by the worker for a second program in the same container)
+WORK_DIR="${WORK_DIR:-/tmp/work}"
+
+compile() {
+    # The worker copies the code into the work directory before compiling
+    if [ ! -f "$WORK_DIR/$SOURCE_FILE" ]; then
+        echo "Error: $SOURCE_FILE not found in $WORK_DIR" >&2
+        exit 2
+    fi
+    cd "$WORK_DIR" || exit 2
+
+    # Compile the code
+    if [ -n "$COMPILE_CMD" ] && ! sh -c "$COMPILE_CMD" > compile_error 2>&1; then
+        echo "Compilation error:" >&2
+        cat compile_error >&2
+        exit 1
+    fi
+}
+
+# Run the compiled program under the CPU and wall time limits, measured by GNU time
+# (hard CPU limit one second later so the program gets SIGXCPU rather than SIGKILL).
+# GNU time writes the usage to its stderr, captured here, while the program gets the real stdout and
+# stderr (fds 3 and 4) and no other descriptor: unlike a file in the work directory, it cannot forge
+# the report. RUN_CMD is a shell command; $ARGS are only split into its arguments, never
+# evaluated nor globbed. Sets EXIT_CODE and USAGE.
+run_program() {
+    cd "$WORK_DIR" || exit 2
+    exec 3>&1 4>&2
+    REPORT=$( (set -f; ulimit -S -t "$CPU_LIMIT"; ulimit -H -t $((CPU_LIMIT + 1)); exec /usr/bin/time -q -f "%U %S %M" timeout "${TIMEOUT}s" sh -c "exec 2>&4 3>&- 4>&- 5>&- 6>&- 7>&- 8>&- 9>&-; exec $RUN_CMD \"\$@\"" sh $ARGS) 2>&1 1>&3; echo "$?")
+    exec 3>&- 4>&-
+    EXIT_CODE=$(printf '%s\n' "$REPORT" | tail -n 1)
+    USAGE=$(printf '%s\n' "$REPORT" | sed '$d' | tail -n 1)
+}
+
+# Report the CPU time (user and system seconds) and peak memory (KB) of the program to the worker
+# on the last line of stderr, nothing if GNU time did not write them (the worker takes them as unknown)
+report_usage() {
+    if [ -n "$USAGE" ]; then
+        echo "EXECUTOR_USAGE $USAGE" >&2
     fi
-else
-    # Test run: always read from stdin (piped via docker exec -i)
-    timeout 5s "${TEMP_DIR}/program"
-fi
-
-# Capture the exit code
-EXIT_CODE=$?
-
-# Check if execution timed out
-if [ $EXIT_CODE -eq 124 ]; then
-    echo "Execution timed out." >&2
-    rm -rf "$TEMP_DIR"
-    exit 1
-fi
-
-# Clean up
-rm -rf "$TEMP_DIR"
-
-# Exit with the same code as the program
-exit $EXIT_CODE
\ No newline at end of file
+}
+
+run() {
+    # Single runs get no input, test runs read the piped stdin (docker exec -i)
+    run_program
+
+    # Check if execution timed out
+    if [ $EXIT_CODE -eq 124 ]; then
+        echo "Execution timed out." >&2
+    fi
+    report_usage
+
+    # Exit with the same code as the program
+    exit $EXIT_CODE
+}
+
+case "$PHASE" in
+    compile) compile ;;
+    run) run ;;
+    *) compile; run ;;
+esac
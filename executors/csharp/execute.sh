@@ -1,20 +1,70 @@
 #!/bin/bash
-set -e
 
-# Fetch code
-curl -s "$CODE_URL" -o Program.cs
+# Phases (PHASE env):
+#   compile  compile the code copied in by the worker with mcs (exit 1 on compilation errors)
+#   run      run the compiled program with the piped stdin (and $ARGS as arguments)
+#   (unset)  both, one after the other
 
-# Compile
-mcs Program.cs
+# Set by the worker for a second program in the same container
+WORK_DIR="${WORK_DIR:-/tmp/work}"
 
-# Warm up JIT
-mono --version > /dev/null
+# How to build and run the code, from the language registry (languages.json)
+SOURCE_FILE="${SOURCE_FILE:-Program.cs}"
+COMPILE_CMD="${COMPILE_CMD-mcs -out:Program.exe Program.cs}"
+RUN_CMD="${RUN_CMD:-mono Program.exe}"
 
-# Logic:
-if [ -n "$SINGLE" ]; then
-    # SINGLE is set → run without input
-    timeout ${TIMEOUT:-8}s mono Program.exe
-else
-    # SINGLE is not set → run with stdin input
-    timeout ${TIMEOUT:-8}s mono Program.exe < /dev/stdin
-fi
+compile() {
+    # The worker copies the code into the work directory before compiling
+    if [ ! -f "$WORK_DIR/$SOURCE_FILE" ]; then
+        echo "Error: $SOURCE_FILE not found in $WORK_DIR" >&2
+        exit 2
+    fi
+    cd "$WORK_DIR"
+
+    # Compile (mcs reports errors on stdout)
+    if [ -n "$COMPILE_CMD" ] && ! sh -c "$COMPILE_CMD" > compile_error 2>&1; then
+        echo "Compilation error:" >&2
+        cat compile_error >&2
+        exit 1
+    fi
+
+    # Warm up JIT
+    mono --version > /dev/null
+}
+
+# Report the CPU time (user and system seconds) and peak memory (KB) of the program to the worker
+# on the last line of stderr, nothing if GNU time did not write them (the worker takes them as unknown)
+report_usage() {
+    if [ -n "$USAGE" ]; then
+        echo "EXECUTOR_USAGE $USAGE" >&2
+    fi
+}
+
+run() {
+    cd "$WORK_DIR"
+
+    # CPU time limit passed by the worker (seconds), hard limit one second later
+    # so the program gets SIGXCPU rather than SIGKILL
+    CPU_LIMIT="${CPU_LIMIT:-8}"
+    ulimit -S -t "$CPU_LIMIT"
+    ulimit -H -t $((CPU_LIMIT + 1))
+
+    # Single runs get no input, test runs read the piped stdin (docker exec -i);
+    # GNU time measures the program and writes the usage to its stderr, captured here, while the
+    # program gets the real stdout and stderr (fds 3 and 4) and no other descriptor: unlike a file in
+    # the work directory, it cannot forge the report. $ARGS are only split into the arguments of
+    # RUN_CMD, never evaluated nor globbed
+    exec 3>&1 4>&2
+    REPORT=$( (set -f; exec /usr/bin/time -q -f "%U %S %M" timeout ${TIMEOUT:-8}s sh -c "exec 2>&4 3>&- 4>&- 5>&- 6>&- 7>&- 8>&- 9>&-; exec $RUN_CMD \"\$@\"" sh $ARGS) 2>&1 1>&3; echo "$?")
+    exec 3>&- 4>&-
+    EXIT_CODE=$(printf '%s\n' "$REPORT" | tail -n 1)
+    USAGE=$(printf '%s\n' "$REPORT" | sed '$d' | tail -n 1)
+    report_usage
+    exit $EXIT_CODE
+}
+
+case "$PHASE" in
+    compile) compile ;;
+    run) run ;;
+    *) compile; run ;;
+esac
@@ -1,48 +1,83 @@
 #!/usr/bin/env node
 
-const { execSync } = require("child_process");
+// Phases (PHASE env):
+//   compile  check the syntax of the code copied in by the worker (exit 1 on syntax errors)
+//   run      run the code with the piped stdin (and $ARGS as arguments);
+//            with INTERACTIVE set, stdin and stdout are passed through as they are (interactive problems)
+//   (unset)  both, one after the other
+
+const { spawnSync } = require("child_process");
 const fs = require("fs");
 const path = require("path");
-const http = require("http");
-const https = require("https");
-const os = require("os");
-
-function runCode(codeFile, input = null) {
-  try {
-    const options = {
-      timeout: 5000,
+
+// Limits passed by the worker (wall seconds, CPU seconds)
+const TIMEOUT = parseFloat(process.env.TIMEOUT || "5");
+const CPU_LIMIT = parseInt(process.env.CPU_LIMIT || "5", 10);
+
+// Exit codes the worker maps to TLE / MLE verdicts
+const EXIT_TIMEOUT = 124;
+const SIGNAL_NUMBERS = { SIGABRT: 6, SIGKILL: 9, SIGSEGV: 11, SIGXCPU: 24 };
+
+// How to build and run the code, from the language registry (languages.json)
+const SOURCE_FILE = process.env.SOURCE_FILE || "main.js";
+const COMPILE_CMD = process.env.COMPILE_CMD !== undefined ? process.env.COMPILE_CMD : "node --check main.js";
+const RUN_CMD = process.env.RUN_CMD || "node main.js";
+
+// Where the worker copies the code (set by the worker for a second program in the same container)
+const WORK_DIR = process.env.WORK_DIR || "/tmp/work";
+const CODE_FILE = path.join(WORK_DIR, SOURCE_FILE);
+
+function runCode(input = null, interactive = false) {
+  const result = spawnSync(
+    "sh",
+    // Hard CPU limit one second later so node gets SIGXCPU rather than SIGKILL. GNU time measures the program
+    // and writes the usage to its stderr, fd 3 here, while the program gets the real stderr and no other
+    // descriptor: unlike a file in the work directory, it cannot forge the report. $ARGS (from the
+    // environment) are only split into the arguments of the program, never evaluated nor globbed
+    [
+      "-c",
+      `set -f; ulimit -S -t ${CPU_LIMIT}; ulimit -H -t ${CPU_LIMIT + 1}; ` +
+        `exec /usr/bin/time -q -f "%U %S %M" ` +
+        `sh -c "exec 2>&4 3>&- 4>&- 5>&- 6>&- 7>&- 8>&- 9>&-; exec ${RUN_CMD} \\"\\$@\\"" sh $ARGS 4>&2 2>&3 3>&-`,
+    ],
+    {
+      cwd: WORK_DIR,
+      timeout: TIMEOUT * 1000,
       encoding: "utf-8",
       input: input || undefined,
-    };
-    const output = execSync(`node ${codeFile}`, options);
-    return { stdout: output, stderr: "" };
-  } catch (error) {
-    if (error.killed || error.signal === "SIGTERM") {
-      return { stdout: "", stderr: "Execution timed out." };
+      maxBuffer: 8 * 1024 * 1024,
+      // Interactive programs talk to the interactor through the inherited stdin/stdout
+      stdio: interactive ? ["inherit", "inherit", "pipe", "pipe"] : ["pipe", "pipe", "pipe", "pipe"],
     }
-    return { stdout: "", stderr: `Error: ${error.message}` };
+  );
+
+  if (result.error && result.error.code === "ETIMEDOUT") {
+    return { stdout: "", stderr: "Execution timed out.", exitCode: EXIT_TIMEOUT, usage: "" };
+  }
+  const usage = ((result.output && result.output[3]) || "").trim().split("\n").pop();
+  if (result.signal) {
+    // Killed by a signal (OOM killer, RLIMIT_CPU): report it like a shell would
+    return {
+      stdout: result.stdout || "",
+      stderr: `${result.stderr || ""}Killed by ${result.signal}`,
+      exitCode: 128 + (SIGNAL_NUMBERS[result.signal] || 15),
+      usage,
+    };
   }
+  return {
+    stdout: result.stdout || "",
+    stderr: result.stderr || "",
+    exitCode: result.status || 0,
+    usage,
+  };
 }
 
-function downloadCode(url) {
-  return new Promise((resolve, reject) => {
-    const client = url.startsWith("https") ? https : http;
-
-    client
-      .get(url, (res) => {
-        if (res.statusCode !== 200) {
-          reject(
-            new Error(`Failed to download code. Status code: ${res.statusCode}`)
-          );
-          return;
-        }
-
-        let data = "";
-        res.on("data", (chunk) => (data += chunk));
-        res.on("end", () => resolve(data));
-      })
-      .on("error", reject);
-  });
+// Report the CPU time (user and system seconds) and peak memory (KB) of the program to the worker
+// on the last line of stderr, nothing if GNU time did not write them (the worker takes them as unknown)
+function reportUsage(usage) {
+  if (usage) {
+    process.stderr.write(`EXECUTOR_USAGE ${usage}\n`);
+  }
 }
 
 function readStdin() {
@@ -55,42 +90,56 @@ function readStdin() {
   });
 }
 
-async function main() {
-  try {
-    const codeUrl = process.env.CODE_URL;
-    const singleMode = process.env.SINGLE;
+async function compile() {
+  // The worker copies the code into the work directory before compiling
+  if (!fs.existsSync(CODE_FILE)) {
+    console.error(`Error: ${SOURCE_FILE} not found in ${WORK_DIR}`);
+    process.exit(2);
+  }
 
-    if (!codeUrl) {
-      //console.log("STDERR:");
-      console.log("Error: CODE_URL environment variable not set.");
+  // Syntax errors are reported as compilation errors
+  if (COMPILE_CMD) {
+    const check = spawnSync("sh", ["-c", COMPILE_CMD], { cwd: WORK_DIR, encoding: "utf-8" });
+    if (check.status !== 0) {
+      process.stderr.write((check.stdout || "") + (check.stderr || ""));
       process.exit(1);
     }
+  }
+}
 
-    const code = await downloadCode(codeUrl);
-    const tempFilePath = path.join(os.tmpdir(), `code-${Date.now()}.js`);
-    fs.writeFileSync(tempFilePath, code);
-
-    let input = null;
-    if (!singleMode) {
-      // Wait for piped stdin if SINGLE is not set
-      input = await readStdin();
-    }
+async function run() {
+  if (process.env.INTERACTIVE) {
+    const { stderr, exitCode, usage } = runCode(null, true);
+    process.stderr.write(stderr);
+    reportUsage(usage);
+    process.exitCode = exitCode;
+    return;
+  }
 
-    const { stdout, stderr } = runCode(tempFilePath, input);
+  let input = null;
+  if (!process.env.SINGLE) {
+    // Wait for piped stdin if SINGLE is not set
+    input = await readStdin();
+  }
 
-    try {
-      fs.unlinkSync(tempFilePath);
-    } catch {}
+  const { stdout, stderr, exitCode, usage } = runCode(input);
+  process.stdout.write(stdout);
+  process.stderr.write(stderr);
+  reportUsage(usage);
+  process.exitCode = exitCode;
+}
 
-    //console.log("STDOUT:");
-    console.log(stdout);
-    //console.log("STDERR:");
-    //console.log(stderr);
-  } catch (error) {
-    console.log("STDERR:");
-    console.log(`Error: ${error.message}`);
-    process.exit(1);
+async function main() {
+  const phase = process.env.PHASE;
+  if (phase !== "run") {
+    await compile();
+  }
+  if (phase !== "compile") {
+    await run();
   }
 }
 
-main();
+main().catch((error) => {
+  console.error(`Error: ${error.message}`);
+  process.exit(2);
+});
@@ -1,24 +1,97 @@
 #!/usr/bin/env python3
-import os, sys, subprocess, tempfile, requests
-
-def run_code(code_file, stdin_input):
-    result = subprocess.run(
-        ["python3", code_file],
-        input=stdin_input,
-        capture_output=True,
-        text=True,
-        timeout=5,
-    )
-    return result.stdout.strip(), result.stderr.strip(), result.returncode
+import os, sys, subprocess, resource
+
+# Phases (PHASE env):
+#   compile  check the syntax of the code copied in by the worker (exit 1 on syntax errors)
+#   run      run the code with the piped stdin (and $ARGS as arguments);
+#            with INTERACTIVE set, stdin and stdout are passed through as they are (interactive problems)
+#   (unset)  both, one after the other
+
+# Limits passed by the worker (wall seconds, CPU seconds)
+TIMEOUT = float(os.environ.get("TIMEOUT", "5"))
+CPU_LIMIT = int(os.environ.get("CPU_LIMIT", "5"))
+
+# How to build and run the code, from the language registry (languages.json)
+SOURCE_FILE = os.environ.get("SOURCE_FILE", "main.py")
+COMPILE_CMD = os.environ.get("COMPILE_CMD", "python3 -m py_compile main.py")
+RUN_CMD = os.environ.get("RUN_CMD", "python3 main.py")
+
+# Where the worker copies the code (set by the worker for a second program in the same container)
+WORK_DIR = os.environ.get("WORK_DIR", "/tmp/work")
+CODE_FILE = os.path.join(WORK_DIR, SOURCE_FILE)
+
+def program_command():
+    # RUN_CMD is a shell command; $ARGS are only split into its arguments, never evaluated nor globbed
+    return ["sh", "-c", f'exec {RUN_CMD} "$@"', "sh", *os.environ.get("ARGS", "").split()]
+
+def limit_cpu():
+    # Hard limit one second later so the program gets SIGXCPU rather than SIGKILL
+    resource.setrlimit(resource.RLIMIT_CPU, (CPU_LIMIT, CPU_LIMIT + 1))
+
+def run_code(stdin_input):
+    try:
+        result = subprocess.run(
+            program_command(),
+            cwd=WORK_DIR,
+            input=stdin_input,
+            capture_output=True,
+            text=True,
+            timeout=TIMEOUT,
+            preexec_fn=limit_cpu,
+        )
+    except subprocess.TimeoutExpired:
+        return "", "Execution timed out.", 124
+    # Killed by a signal (OOM killer, RLIMIT_CPU): report it like a shell would
+    if result.returncode < 0:
+        return result.stdout, result.stderr, 128 - result.returncode
+    return result.stdout, result.stderr, result.returncode
+
+def run_interactive():
+    # The program talks to the interactor itself, so nothing is read or captured here
+    try:
+        result = subprocess.run(
+            program_command(),
+            cwd=WORK_DIR,
+            timeout=TIMEOUT,
+            preexec_fn=limit_cpu,
+        )
+    except subprocess.TimeoutExpired:
+        print("Execution timed out.", file=sys.stderr)
+        return 124
+    if result.returncode < 0:
+        return 128 - result.returncode
+    return result.returncode
+
+def compile_code():
+    # The worker copies the code into the work directory before compiling
+    if not os.path.isfile(CODE_FILE):
+        print(f"Error: {SOURCE_FILE} not found in {WORK_DIR}", file=sys.stderr)
+        sys.exit(2)
+
+    # Syntax errors are reported as compilation errors
+    if COMPILE_CMD:
+        check = subprocess.run(COMPILE_CMD, shell=True, cwd=WORK_DIR, capture_output=True, text=True)
+        if check.returncode != 0:
+            sys.stderr.write(check.stdout + check.stderr)
+            sys.exit(1)
+
+def report_usage(before):
+    # Report the CPU time (user and system seconds) and peak memory (KB) of the program
+    # to the worker on the last line of stderr
+    after = resource.getrusage(resource.RUSAGE_CHILDREN)
+    user = after.ru_utime - before.ru_utime
+    system = after.ru_stime - before.ru_stime
+    print(f"EXECUTOR_USAGE {user:.3f} {system:.3f} {after.ru_maxrss}", file=sys.stderr)
+
+def run():
+    before = resource.getrusage(resource.RUSAGE_CHILDREN)
+    if os.environ.get("INTERACTIVE"):
+        retcode = run_interactive()
+        report_usage(before)
+        sys.exit(retcode)
 
-if __name__ == "__main__":
-    code_url = os.environ.get("CODE_URL")
     is_single_run = os.environ.get("SINGLE") is not None
     input_data = ""
-    
-    if not code_url:
-        print("Error: CODE_URL not set", file=sys.stderr)
-        sys.exit(1)
 
     if is_single_run:
         # Single run: read from stdin if available
@@ -28,25 +101,18 @@ if __name__ == "__main__":
         # Test run: always read from stdin (piped via docker exec -i)
         input_data = sys.stdin.read()
 
-    # Fetch user-submitted code
-    r = requests.get(code_url)
-    if r.status_code != 200:
-        print(f"Failed to download code: {r.status_code}", file=sys.stderr)
-        sys.exit(1)
+    stdout, stderr, retcode = run_code(input_data)
+    sys.stdout.write(stdout)
+    sys.stderr.write(stderr)
+    report_usage(before)
+    sys.exit(retcode)
 
-    code = r.text
-    with tempfile.NamedTemporaryFile(suffix=".py", delete=False) as tmp:
-        tmp.write(code.encode("utf-8"))
-        code_file = tmp.name
-
-    stdout, stderr, retcode = run_code(code_file, input_data)
-    try:
-        os.unlink(code_file)
-    except:
-        pass
-
-    if retcode == 0 and not stderr:
-        print(stdout)
+if __name__ == "__main__":
+    phase = os.environ.get("PHASE")
+    if phase == "compile":
+        compile_code()
+    elif phase == "run":
+        run()
     else:
-        print((stdout + "\n" + stderr).strip())
-        sys.exit(retcode or 1)
\ No newline at end of file
+        compile_code()
+        run()
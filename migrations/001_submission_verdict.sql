@@ -0,0 +1,69 @@
+--
+-- Store the structured verdict (AC, WA, CE, RE, TLE, MLE, OLE) of every submission
+--
+
+ALTER TABLE public.submission ADD COLUMN verdict character varying(3);
+
+UPDATE public.submission SET verdict = CASE WHEN correct THEN 'AC' ELSE 'WA' END WHERE verdict IS NULL;
+
+DROP PROCEDURE IF EXISTS public.create_submission(text, integer, boolean, text, integer, text);
+
+CREATE PROCEDURE public.create_submission(IN p_user_id text, IN p_problem_id integer, IN p_correct boolean, IN p_language text, IN p_time integer, IN p_submission_result text, IN p_verdict text)
+    LANGUAGE plpgsql
+    AS $$
+DECLARE
+    v_points INT := 0;
+    v_already_solved BOOLEAN := FALSE;
+BEGIN
+    -- Only check if correct
+    IF p_correct THEN
+        -- Check if user has already solved this problem correctly
+        SELECT EXISTS (
+            SELECT 1 FROM submission
+            WHERE user_id = p_user_id
+              AND problem_id = p_problem_id
+              AND correct = true
+        )
+        INTO v_already_solved;
+
+        -- If not already solved, calculate points from difficulty
+        IF NOT v_already_solved THEN
+            SELECT difficulty * 20
+            INTO v_points
+            FROM problem
+            WHERE problem_id = p_problem_id;
+        END IF;
+    END IF;
+
+    -- Insert new submission
+    INSERT INTO submission (
+        user_id,
+        problem_id,
+        "date",
+        points,
+        correct,
+        language,
+        "time",
+        submission_result,
+        verdict
+    )
+    VALUES (
+        p_user_id,
+        p_problem_id,
+        NOW(),
+        v_points,
+        p_correct,
+        p_language,
+        p_time,
+        p_submission_result,
+        p_verdict
+    );
+
+    -- Add points to user only if this is the first correct
+    IF v_points > 0 THEN
+        UPDATE "User"
+        SET points = points + v_points
+        WHERE user_id = p_user_id;
+    END IF;
+END;
+$$;
@@ -0,0 +1,12 @@
+--
+-- Per-problem output checker: exact, tokens, float, unordered or special (checker program)
+--
+
+ALTER TABLE public.problem ADD COLUMN checker_mode character varying(20) DEFAULT 'exact' NOT NULL;
+ALTER TABLE public.problem ADD COLUMN checker_abs_epsilon double precision;
+ALTER TABLE public.problem ADD COLUMN checker_rel_epsilon double precision;
+ALTER TABLE public.problem ADD COLUMN checker_language character varying(50);
+ALTER TABLE public.problem ADD COLUMN checker_code text;
+
+ALTER TABLE public.problem
+    ADD CONSTRAINT problem_checker_mode_check CHECK (checker_mode IN ('exact', 'tokens', 'float', 'unordered', 'special'));
@@ -0,0 +1,77 @@
+--
+-- One submission per job: the worker stores submissions keyed by the job id,
+-- so retries (or a requeued job) never insert a second row
+--
+
+ALTER TABLE public.submission ADD COLUMN job_id uuid;
+
+ALTER TABLE ONLY public.submission
+    ADD CONSTRAINT submission_job_id_key UNIQUE (job_id);
+
+DROP PROCEDURE IF EXISTS public.create_submission(text, integer, boolean, text, integer, text, text);
+
+CREATE PROCEDURE public.create_submission(IN p_job_id uuid, IN p_user_id text, IN p_problem_id integer, IN p_correct boolean, IN p_language text, IN p_time integer, IN p_submission_result text, IN p_verdict text)
+    LANGUAGE plpgsql
+    AS $$
+DECLARE
+    v_points INT := 0;
+    v_already_solved BOOLEAN := FALSE;
+    v_inserted INT := 0;
+BEGIN
+    -- Only check if correct
+    IF p_correct THEN
+        -- Check if user has already solved this problem correctly
+        SELECT EXISTS (
+            SELECT 1 FROM submission
+            WHERE user_id = p_user_id
+              AND problem_id = p_problem_id
+              AND correct = true
+        )
+        INTO v_already_solved;
+
+        -- If not already solved, calculate points from difficulty
+        IF NOT v_already_solved THEN
+            SELECT difficulty * 20
+            INTO v_points
+            FROM problem
+            WHERE problem_id = p_problem_id;
+        END IF;
+    END IF;
+
+    -- Insert new submission (nothing if this job was already stored)
+    INSERT INTO submission (
+        job_id,
+        user_id,
+        problem_id,
+        "date",
+        points,
+        correct,
+        language,
+        "time",
+        submission_result,
+        verdict
+    )
+    VALUES (
+        p_job_id,
+        p_user_id,
+        p_problem_id,
+        NOW(),
+        v_points,
+        p_correct,
+        p_language,
+        p_time,
+        p_submission_result,
+        p_verdict
+    )
+    ON CONFLICT (job_id) DO NOTHING;
+
+    GET DIAGNOSTICS v_inserted = ROW_COUNT;
+
+    -- Add points to user only if this is the first correct
+    IF v_inserted > 0 AND v_points > 0 THEN
+        UPDATE "User"
+        SET points = points + v_points
+        WHERE user_id = p_user_id;
+    END IF;
+END;
+$$;
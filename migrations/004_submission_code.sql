@@ -0,0 +1,84 @@
+--
+-- Keep the submitted code, the language version and the full verdict breakdown
+-- (per-test results, exit code, stderr) of every submission
+--
+
+ALTER TABLE public.submission ADD COLUMN code text;
+ALTER TABLE public.submission ADD COLUMN language_version character varying(100);
+ALTER TABLE public.submission ADD COLUMN details jsonb;
+
+CREATE INDEX submission_user_id_date_idx ON public.submission USING btree (user_id, date DESC);
+
+DROP PROCEDURE IF EXISTS public.create_submission(uuid, text, integer, boolean, text, integer, text, text);
+
+CREATE PROCEDURE public.create_submission(IN p_job_id uuid, IN p_user_id text, IN p_problem_id integer, IN p_correct boolean, IN p_language text, IN p_time integer, IN p_submission_result text, IN p_verdict text, IN p_code text, IN p_language_version text, IN p_details jsonb)
+    LANGUAGE plpgsql
+    AS $$
+DECLARE
+    v_points INT := 0;
+    v_already_solved BOOLEAN := FALSE;
+    v_inserted INT := 0;
+BEGIN
+    -- Only check if correct
+    IF p_correct THEN
+        -- Check if user has already solved this problem correctly
+        SELECT EXISTS (
+            SELECT 1 FROM submission
+            WHERE user_id = p_user_id
+              AND problem_id = p_problem_id
+              AND correct = true
+        )
+        INTO v_already_solved;
+
+        -- If not already solved, calculate points from difficulty
+        IF NOT v_already_solved THEN
+            SELECT difficulty * 20
+            INTO v_points
+            FROM problem
+            WHERE problem_id = p_problem_id;
+        END IF;
+    END IF;
+
+    -- Insert new submission (nothing if this job was already stored)
+    INSERT INTO submission (
+        job_id,
+        user_id,
+        problem_id,
+        "date",
+        points,
+        correct,
+        language,
+        "time",
+        submission_result,
+        verdict,
+        code,
+        language_version,
+        details
+    )
+    VALUES (
+        p_job_id,
+        p_user_id,
+        p_problem_id,
+        NOW(),
+        v_points,
+        p_correct,
+        p_language,
+        p_time,
+        p_submission_result,
+        p_verdict,
+        p_code,
+        p_language_version,
+        p_details
+    )
+    ON CONFLICT (job_id) DO NOTHING;
+
+    GET DIAGNOSTICS v_inserted = ROW_COUNT;
+
+    -- Add points to user only if this is the first correct
+    IF v_inserted > 0 AND v_points > 0 THEN
+        UPDATE "User"
+        SET points = points + v_points
+        WHERE user_id = p_user_id;
+    END IF;
+END;
+$$;
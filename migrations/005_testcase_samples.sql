@@ -0,0 +1,5 @@
+--
+-- Sample test cases: public, and the only ones checked in "run" mode
+--
+
+ALTER TABLE public.testcases ADD COLUMN is_sample boolean DEFAULT false NOT NULL;
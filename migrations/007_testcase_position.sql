@@ -0,0 +1,15 @@
+--
+-- Run order of the test cases of a problem (set by uploads and the reorder endpoint)
+--
+
+ALTER TABLE public.testcases ADD COLUMN position integer;
+
+UPDATE public.testcases t
+SET position = o.position
+FROM (
+    SELECT testcase_id, ROW_NUMBER() OVER (PARTITION BY problem_id ORDER BY testcase_id) AS position
+    FROM public.testcases
+) o
+WHERE o.testcase_id = t.testcase_id;
+
+CREATE INDEX testcases_problem_id_position_idx ON public.testcases USING btree (problem_id, position);
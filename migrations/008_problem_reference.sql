@@ -0,0 +1,17 @@
+--
+-- Reference solutions: when a problem has one, it is run on every test case (with the problem's
+-- limits and checker) after each change, and the problem is only published if it passes them all.
+-- The slowest test gives a suggested timelimit (seconds).
+--
+
+ALTER TABLE public.problem ADD COLUMN reference_language character varying(50);
+ALTER TABLE public.problem ADD COLUMN reference_code text;
+ALTER TABLE public.problem ADD COLUMN published boolean DEFAULT true NOT NULL;
+ALTER TABLE public.problem ADD COLUMN validation_status character varying(20);
+ALTER TABLE public.problem ADD COLUMN validation_message text;
+ALTER TABLE public.problem ADD COLUMN validation_job_id uuid;
+ALTER TABLE public.problem ADD COLUMN reference_time_ms integer;
+ALTER TABLE public.problem ADD COLUMN suggested_timelimit integer;
+
+ALTER TABLE public.problem
+    ADD CONSTRAINT problem_validation_status_check CHECK (validation_status IN ('pending', 'valid', 'invalid'));
@@ -0,0 +1,12 @@
+--
+-- Test generators and input validators: the worker generates the inputs from a list of generator
+-- arguments, validates them and produces the outputs with the reference solution. Generated test
+-- cases keep their arguments, and are replaced when the problem's tests are generated again.
+--
+
+ALTER TABLE public.problem ADD COLUMN generator_language character varying(50);
+ALTER TABLE public.problem ADD COLUMN generator_code text;
+ALTER TABLE public.problem ADD COLUMN validator_language character varying(50);
+ALTER TABLE public.problem ADD COLUMN validator_code text;
+
+ALTER TABLE public.testcases ADD COLUMN generator_args text;
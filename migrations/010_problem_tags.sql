@@ -0,0 +1,5 @@
+--
+-- Problem tags (uploads, edits and problem packages)
+--
+
+ALTER TABLE public.problem ADD COLUMN tags text[] DEFAULT '{}'::text[] NOT NULL;
@@ -0,0 +1,7 @@
+--
+-- Interactive problems: checker mode 'interactive', the checker program is the interactor
+--
+
+ALTER TABLE public.problem DROP CONSTRAINT problem_checker_mode_check;
+ALTER TABLE public.problem
+    ADD CONSTRAINT problem_checker_mode_check CHECK (checker_mode IN ('exact', 'tokens', 'float', 'unordered', 'special', 'interactive'));
@@ -0,0 +1,9 @@
+--
+-- Function problems: the signature the solutions implement, NULL for stdin/stdout problems
+--
+
+ALTER TABLE public.problem ADD COLUMN function_signature jsonb;
+
+-- The harness reads the whole input at once, it cannot talk to an interactor
+ALTER TABLE public.problem
+    ADD CONSTRAINT problem_function_not_interactive CHECK (function_signature IS NULL OR checker_mode <> 'interactive');
@@ -0,0 +1,89 @@
+--
+-- Resource usage of submissions: "time" is the wall time of the whole job, including the
+-- container overhead. cpu_time_ms and memory_kb are what the program itself used (the most
+-- of any test), as measured by the executors; NULL for older submissions or when not reported.
+--
+
+ALTER TABLE public.submission ADD COLUMN cpu_time_ms integer;
+ALTER TABLE public.submission ADD COLUMN memory_kb integer;
+
+DROP PROCEDURE IF EXISTS public.create_submission(uuid, text, integer, boolean, text, integer, text, text, text, text, jsonb, numeric);
+
+CREATE PROCEDURE public.create_submission(IN p_job_id uuid, IN p_user_id text, IN p_problem_id integer, IN p_correct boolean, IN p_language text, IN p_time integer, IN p_submission_result text, IN p_verdict text, IN p_code text, IN p_language_version text, IN p_details jsonb, IN p_score numeric, IN p_cpu_time_ms integer, IN p_memory_kb integer)
+    LANGUAGE plpgsql
+    AS $$
+DECLARE
+    v_max_points INT := 0;
+    v_best_score NUMERIC := 0;
+    v_points INT := 0;
+    v_inserted INT := 0;
+BEGIN
+    -- One submission of a user at a time, so two of them cannot earn the same points
+    PERFORM 1 FROM "User" WHERE user_id = p_user_id FOR UPDATE;
+
+    IF p_score > 0 THEN
+        SELECT COALESCE(difficulty, 0) * 20
+        INTO v_max_points
+        FROM problem
+        WHERE problem_id = p_problem_id;
+
+        -- Best score of the user on this problem so far
+        SELECT COALESCE(MAX(COALESCE(score, CASE WHEN correct THEN 100 ELSE 0 END)), 0)
+        INTO v_best_score
+        FROM submission
+        WHERE user_id = p_user_id
+          AND problem_id = p_problem_id;
+
+        -- Only the improvement earns points
+        v_points := GREATEST(0, ROUND(v_max_points * p_score / 100) - ROUND(v_max_points * v_best_score / 100));
+    END IF;
+
+    -- Insert new submission (nothing if this job was already stored)
+    INSERT INTO submission (
+        job_id,
+        user_id,
+        problem_id,
+        "date",
+        points,
+        correct,
+        language,
+        "time",
+        submission_result,
+        verdict,
+        code,
+        language_version,
+        details,
+        score,
+        cpu_time_ms,
+        memory_kb
+    )
+    VALUES (
+        p_job_id,
+        p_user_id,
+        p_problem_id,
+        NOW(),
+        v_points,
+        p_correct,
+        p_language,
+        p_time,
+        p_submission_result,
+        p_verdict,
+        p_code,
+        p_language_version,
+        p_details,
+        p_score,
+        p_cpu_time_ms,
+        p_memory_kb
+    )
+    ON CONFLICT (job_id) DO NOTHING;
+
+    GET DIAGNOSTICS v_inserted = ROW_COUNT;
+
+    -- Add the improvement to the user's points
+    IF v_inserted > 0 AND v_points > 0 THEN
+        UPDATE "User"
+        SET points = points + v_points
+        WHERE user_id = p_user_id;
+    END IF;
+END;
+$$;
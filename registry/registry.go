@@ -0,0 +1,70 @@
+// Package registry reads the language registry (languages.json), shared by the API and the worker
+// so that both accept the same languages.
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Where the language registry is read from, relative to the working directory
+const DefaultFile = "languages.json"
+
+// Language is one entry of the language registry. The executor image, entrypoint and sandbox are
+// only read by the worker.
+type Language struct {
+	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	Version       string  `json:"version"`
+	Image         string  `json:"image"`       // executor image
+	Entrypoint    string  `json:"entrypoint"`  // executor script inside the image
+	SourceFile    string  `json:"source_file"` // file the code is saved to, in the work directory
+	Compile       string  `json:"compile"`     // compile (or syntax check) command, empty for none
+	Run           string  `json:"run"`         // command that runs the program
+	TimeLimitMs   int     `json:"time_limit_ms"`
+	MemoryLimitMB int     `json:"memory_limit_mb"`
+	Sandbox       Sandbox `json:"sandbox"` // container profile of the docker backend
+}
+
+// Sandbox is the container profile of a language (the "sandbox" object in languages.json).
+// Executor containers never have network, drop every capability and cannot gain privileges;
+// the rest can be set per language, unset fields take the worker's defaults.
+type Sandbox struct {
+	User       string `json:"user"`         // uid:gid the code runs as
+	ReadOnly   *bool  `json:"read_only"`    // read-only root filesystem, true by default
+	TmpfsMB    int    `json:"tmpfs_mb"`     // size of the tmpfs on /tmp (counts towards the container memory)
+	Seccomp    string `json:"seccomp"`      // seccomp profile file, empty for Docker's default profile
+	FileSizeMB int    `json:"file_size_mb"` // largest file a process may write
+	OpenFiles  int    `json:"open_files"`   // file descriptors a process may have open
+}
+
+// Load reads the language registry from LANGUAGES_FILE (languages.json by default). It returns the
+// languages in registry order and by id.
+func Load() ([]Language, map[string]Language, error) {
+	path := os.Getenv("LANGUAGES_FILE")
+	if path == "" {
+		path = DefaultFile
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read language registry: %v", err)
+	}
+	var list []Language
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse language registry %s: %v", path, err)
+	}
+
+	byID := make(map[string]Language, len(list))
+	for _, lang := range list {
+		if lang.ID == "" || lang.Image == "" || lang.Entrypoint == "" || lang.SourceFile == "" || lang.Run == "" {
+			return nil, nil, fmt.Errorf("language %q in %s needs id, image, entrypoint, source_file and run", lang.ID, path)
+		}
+		if _, exists := byID[lang.ID]; exists {
+			return nil, nil, fmt.Errorf("language %q is defined twice in %s", lang.ID, path)
+		}
+		byID[lang.ID] = lang
+	}
+	return list, byID, nil
+}
@@ -0,0 +1,53 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	t.Setenv("LANGUAGES_FILE", filepath.Join("..", DefaultFile))
+	list, byID, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(list) == 0 || len(byID) != len(list) {
+		t.Fatalf("got %d languages, %d by id", len(list), len(byID))
+	}
+	for _, lang := range list {
+		if byID[lang.ID].Image != lang.Image {
+			t.Errorf("language %s: lookup by id differs", lang.ID)
+		}
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	const valid = `{"id": "c", "image": "executor-c", "entrypoint": "/app/execute.sh", "source_file": "main.c", "run": "./program"}`
+	tests := []struct {
+		name     string
+		registry string
+		want     string
+	}{
+		{"not JSON", `[{`, "failed to parse"},
+		{"without id", `[{"image": "executor-c", "entrypoint": "/app/execute.sh", "source_file": "main.c", "run": "./program"}]`, "needs id"},
+		{"without image", `[{"id": "c", "entrypoint": "/app/execute.sh", "source_file": "main.c", "run": "./program"}]`, "needs id, image"},
+		{"without entrypoint", `[{"id": "c", "image": "executor-c", "source_file": "main.c", "run": "./program"}]`, "needs id, image"},
+		{"without source file", `[{"id": "c", "image": "executor-c", "entrypoint": "/app/execute.sh", "run": "./program"}]`, "needs id, image"},
+		{"without run", `[{"id": "c", "image": "executor-c", "entrypoint": "/app/execute.sh", "source_file": "main.c"}]`, "needs id, image"},
+		{"defined twice", "[" + valid + ", " + valid + "]", "defined twice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "languages.json")
+			if err := os.WriteFile(path, []byte(tt.registry), 0644); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("LANGUAGES_FILE", path)
+			if _, _, err := Load(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load error %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
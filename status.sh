@@ -70,7 +70,7 @@ echo ""
 
 # Check executor images
 echo "Executor Images:"
-for LANG in python javascript cpp csharp; do
+for LANG in python javascript cpp csharp c go java rust; do
     if docker images | grep -q "${LANG}-executor"; then
         echo "✅ ${LANG}-executor image is available"
     else
@@ -43,6 +43,42 @@ CSHARP_RESPONSE=$(curl -s -X POST "$API_URL/execute" \
 CSHARP_JOB_ID=$(echo $CSHARP_RESPONSE | sed 's/.*"job_id": "\([^"]*\)".*/\1/')
 echo "C# job ID: $CSHARP_JOB_ID"
 
+# Test C code execution
+echo "Testing C code execution..."
+C_RESPONSE=$(curl -s -X POST "$API_URL/execute" \
+  -H "Content-Type: application/json" \
+  -d '{"language":"c","code":"#include <stdio.h>\nint main() {\n  printf(\"Hello from C!\\n\");\n  return 0;\n}"}')
+
+C_JOB_ID=$(echo $C_RESPONSE | sed 's/.*"job_id": "\([^"]*\)".*/\1/')
+echo "C job ID: $C_JOB_ID"
+
+# Test Go code execution
+echo "Testing Go code execution..."
+GO_RESPONSE=$(curl -s -X POST "$API_URL/execute" \
+  -H "Content-Type: application/json" \
+  -d '{"language":"go","code":"package main\nimport \"fmt\"\nfunc main() {\n  fmt.Println(\"Hello from Go!\")\n}"}')
+
+GO_JOB_ID=$(echo $GO_RESPONSE | sed 's/.*"job_id": "\([^"]*\)".*/\1/')
+echo "Go job ID: $GO_JOB_ID"
+
+# Test Java code execution
+echo "Testing Java code execution..."
+JAVA_RESPONSE=$(curl -s -X POST "$API_URL/execute" \
+  -H "Content-Type: application/json" \
+  -d '{"language":"java","code":"public class Main {\n  public static void main(String[] args) {\n    System.out.println(\"Hello from Java!\");\n  }\n}"}')
+
+JAVA_JOB_ID=$(echo $JAVA_RESPONSE | sed 's/.*"job_id": "\([^"]*\)".*/\1/')
+echo "Java job ID: $JAVA_JOB_ID"
+
+# Test Rust code execution
+echo "Testing Rust code execution..."
+RUST_RESPONSE=$(curl -s -X POST "$API_URL/execute" \
+  -H "Content-Type: application/json" \
+  -d '{"language":"rust","code":"fn main() {\n  println!(\"Hello from Rust!\");\n}"}')
+
+RUST_JOB_ID=$(echo $RUST_RESPONSE | sed 's/.*"job_id": "\([^"]*\)".*/\1/')
+echo "Rust job ID: $RUST_JOB_ID"
+
 # Wait for jobs to complete
 echo "Waiting for jobs to complete..."
 sleep 10
@@ -67,4 +103,24 @@ echo "Checking C# result..."
 curl -s "$API_URL/result/$CSHARP_JOB_ID"
 echo ""
 
+# Check C result
+echo "Checking C result..."
+curl -s "$API_URL/result/$C_JOB_ID"
+echo ""
+
+# Check Go result
+echo "Checking Go result..."
+curl -s "$API_URL/result/$GO_JOB_ID"
+echo ""
+
+# Check Java result
+echo "Checking Java result..."
+curl -s "$API_URL/result/$JAVA_JOB_ID"
+echo ""
+
+# Check Rust result
+echo "Checking Rust result..."
+curl -s "$API_URL/result/$RUST_JOB_ID"
+echo ""
+
 echo "Test complete!" 
\ No newline at end of file
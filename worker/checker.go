@@ -0,0 +1,369 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Checker modes, stored per problem in problem.checker_mode
+const (
+	CheckerExact       = "exact"       // whole output equal after trimming surrounding whitespace
+	CheckerTokens      = "tokens"      // same whitespace-separated tokens
+	CheckerFloat       = "float"       // tokens, numbers compared with an absolute/relative epsilon
+	CheckerUnordered   = "unordered"   // same lines in any order
+	CheckerSpecial     = "special"     // admin-supplied checker program ("special judge")
+	CheckerInteractive = "interactive" // admin-supplied interactor talking to the program, see interactiveJudge
+)
+
+// Epsilon used by the float checker when the problem does not set one
+const defaultEpsilon = 1e-6
+
+// Where the special judge finds the test files (relative to its work directory),
+// passed to it as arguments in testlib order
+const (
+	judgeInputFile  = "judge/input.txt"
+	judgeOutputFile = "judge/output.txt"
+	judgeAnswerFile = "judge/answer.txt"
+)
+
+// Exit codes of a special judge (testlib conventions)
+const (
+	judgeExitOK          = 0
+	judgeExitWrongAnswer = 1
+	judgeExitPresentErr  = 2
+)
+
+// Limits for running a special judge on one test
+var checkerLimits = Limits{CPUTime: 10 * time.Second, WallTime: 20 * time.Second, MemoryMB: 256}
+
+// CheckerSpec says how a problem's outputs are compared
+type CheckerSpec struct {
+	Mode       string  `json:"mode"`                  // one of the Checker* modes, "" = exact
+	AbsEpsilon float64 `json:"abs_epsilon,omitempty"` // float mode
+	RelEpsilon float64 `json:"rel_epsilon,omitempty"` // float mode
+	Language   string  `json:"language,omitempty"`    // special and interactive modes: checker / interactor language
+	Code       string  `json:"code,omitempty"`        // special and interactive modes: checker / interactor source
+}
+
+// Checker compares the output of one test case with the expected output.
+// It returns AC or WA (IE if the checker itself failed) and an optional comment.
+type Checker interface {
+	Check(input, expected, actual string) (Verdict, string)
+}
+
+// newChecker builds the checker for a job. Special judges hold an executor and must be closed.
+func newChecker(job Job) (Checker, error) {
+	spec := job.Checker
+	if job.Function != nil && spec.Mode != CheckerSpecial && spec.Mode != CheckerInteractive {
+		return newFunctionChecker(spec), nil
+	}
+	switch spec.Mode {
+	case "", CheckerExact:
+		return exactChecker{}, nil
+	case CheckerTokens:
+		return tokenChecker{}, nil
+	case CheckerFloat:
+		return newFloatChecker(spec), nil
+	case CheckerUnordered:
+		return unorderedChecker{}, nil
+	case CheckerSpecial:
+		return newSpecialJudge(job.ID+"-checker", spec)
+	case CheckerInteractive:
+		return nil, fmt.Errorf("interactive problems are judged by their interactor, not by comparing outputs")
+	}
+	return nil, fmt.Errorf("unknown checker mode: %s", spec.Mode)
+}
+
+// exactChecker is the original comparison: equal once surrounding whitespace is trimmed
+type exactChecker struct{}
+
+func (exactChecker) Check(input, expected, actual string) (Verdict, string) {
+	if strings.TrimSpace(actual) == strings.TrimSpace(expected) {
+		return VerdictAccepted, ""
+	}
+	return VerdictWrongAnswer, ""
+}
+
+// tokenChecker ignores how tokens are separated (spaces, tabs, newlines)
+type tokenChecker struct{}
+
+func (tokenChecker) Check(input, expected, actual string) (Verdict, string) {
+	return compareTokens(expected, actual, func(e, a string) bool { return e == a })
+}
+
+// floatChecker compares tokens, allowing numbers to differ by an absolute or relative epsilon
+type floatChecker struct {
+	absEpsilon float64
+	relEpsilon float64
+}
+
+func newFloatChecker(spec CheckerSpec) floatChecker {
+	if spec.AbsEpsilon <= 0 && spec.RelEpsilon <= 0 {
+		spec.AbsEpsilon, spec.RelEpsilon = defaultEpsilon, defaultEpsilon
+	}
+	return floatChecker{absEpsilon: spec.AbsEpsilon, relEpsilon: spec.RelEpsilon}
+}
+
+func (c floatChecker) Check(input, expected, actual string) (Verdict, string) {
+	return compareTokens(expected, actual, func(e, a string) bool {
+		want, errE := strconv.ParseFloat(e, 64)
+		got, errA := strconv.ParseFloat(a, 64)
+		if errE != nil || errA != nil {
+			return e == a
+		}
+		return c.close(want, got)
+	})
+}
+
+// close tells if got is within the epsilons of want
+func (c floatChecker) close(want, got float64) bool {
+	if math.IsNaN(want) || math.IsNaN(got) {
+		return math.IsNaN(want) && math.IsNaN(got)
+	}
+	// Equal infinities have a NaN difference, and any other value is infinitely far from an infinity
+	// (which the relative epsilon would scale to infinity too)
+	if want == got {
+		return true
+	}
+	if math.IsInf(want, 0) || math.IsInf(got, 0) {
+		return false
+	}
+	diff := math.Abs(want - got)
+	return diff <= c.absEpsilon || diff <= c.relEpsilon*math.Abs(want)
+}
+
+// compareTokens compares whitespace-separated tokens pairwise with equal
+func compareTokens(expected, actual string, equal func(e, a string) bool) (Verdict, string) {
+	want := strings.Fields(expected)
+	got := strings.Fields(actual)
+	for i := 0; i < len(want) && i < len(got); i++ {
+		if !equal(want[i], got[i]) {
+			return VerdictWrongAnswer, fmt.Sprintf("token %d differs: expected %q, found %q", i+1, want[i], got[i])
+		}
+	}
+	if len(want) != len(got) {
+		return VerdictWrongAnswer, fmt.Sprintf("expected %d tokens, found %d", len(want), len(got))
+	}
+	return VerdictAccepted, ""
+}
+
+// unorderedChecker accepts any permutation of the expected lines (blank lines are ignored)
+type unorderedChecker struct{}
+
+func (unorderedChecker) Check(input, expected, actual string) (Verdict, string) {
+	want := normalizedLines(expected)
+	got := normalizedLines(actual)
+	if len(want) != len(got) {
+		return VerdictWrongAnswer, fmt.Sprintf("expected %d lines, found %d", len(want), len(got))
+	}
+	sort.Strings(want)
+	sort.Strings(got)
+	for i := range want {
+		if want[i] != got[i] {
+			return VerdictWrongAnswer, fmt.Sprintf("line %q is not expected", got[i])
+		}
+	}
+	return VerdictAccepted, ""
+}
+
+// normalizedLines splits s into non-blank lines with whitespace runs collapsed
+func normalizedLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			lines = append(lines, strings.Join(fields, " "))
+		}
+	}
+	return lines
+}
+
+// functionChecker compares the results of function problems (Job.Function) as JSON values:
+// numbers by value, within the epsilons in float mode, and the elements of a returned array in
+// any order in unordered mode. The other comparison modes are all the same for JSON.
+type functionChecker struct {
+	float     *floatChecker
+	unordered bool
+}
+
+func newFunctionChecker(spec CheckerSpec) functionChecker {
+	c := functionChecker{unordered: spec.Mode == CheckerUnordered}
+	if spec.Mode == CheckerFloat {
+		float := newFloatChecker(spec)
+		c.float = &float
+	}
+	return c
+}
+
+func (c functionChecker) Check(input, expected, actual string) (Verdict, string) {
+	want, err := decodeJSON(expected)
+	if err != nil {
+		return VerdictInternalError, fmt.Sprintf("the expected output is not JSON: %v", err)
+	}
+	got, err := decodeJSON(actual)
+	if err != nil {
+		return VerdictWrongAnswer, fmt.Sprintf("the result is not JSON: %v", err)
+	}
+	if c.unordered {
+		if w, ok := want.([]any); ok {
+			sortJSON(w)
+		}
+		if g, ok := got.([]any); ok {
+			sortJSON(g)
+		}
+	}
+	if message := c.compare(want, got, "result"); message != "" {
+		return VerdictWrongAnswer, message
+	}
+	return VerdictAccepted, ""
+}
+
+// compare returns why got differs from want, "" if it does not
+func (c functionChecker) compare(want, got any, path string) string {
+	differs := func() string {
+		return fmt.Sprintf("%s: expected %s, found %s", path, encodeJSON(want), encodeJSON(got))
+	}
+	switch w := want.(type) {
+	case json.Number:
+		g, ok := got.(json.Number)
+		if !ok {
+			return differs()
+		}
+		wi, errW := w.Int64()
+		gi, errG := g.Int64()
+		if errW == nil && errG == nil {
+			if wi != gi {
+				return differs()
+			}
+			return ""
+		}
+		wf, errW := w.Float64()
+		gf, errG := g.Float64()
+		if errW != nil || errG != nil {
+			return differs()
+		}
+		if c.float != nil && c.float.close(wf, gf) || c.float == nil && wf == gf {
+			return ""
+		}
+		return differs()
+	case []any:
+		g, ok := got.([]any)
+		if !ok {
+			return differs()
+		}
+		if len(w) != len(g) {
+			return fmt.Sprintf("%s: expected %d elements, found %d", path, len(w), len(g))
+		}
+		for i := range w {
+			if message := c.compare(w[i], g[i], fmt.Sprintf("%s[%d]", path, i)); message != "" {
+				return message
+			}
+		}
+		return ""
+	}
+	if !reflect.DeepEqual(want, got) {
+		return differs()
+	}
+	return ""
+}
+
+// decodeJSON decodes the single JSON value of s, keeping numbers as json.Number
+func decodeJSON(s string) (any, error) {
+	decoder := json.NewDecoder(strings.NewReader(s))
+	decoder.UseNumber()
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the value")
+	}
+	return v, nil
+}
+
+func encodeJSON(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// sortJSON sorts values by their JSON encoding, numbers compared as float64 so 2 and 2.0 sort together
+func sortJSON(values []any) {
+	key := func(v any) string {
+		return encodeJSON(canonicalJSON(v))
+	}
+	sort.SliceStable(values, func(i, j int) bool { return key(values[i]) < key(values[j]) })
+}
+
+func canonicalJSON(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+	case []any:
+		c := make([]any, len(v))
+		for i := range v {
+			c[i] = canonicalJSON(v[i])
+		}
+		return c
+	}
+	return v
+}
+
+// specialJudge runs an admin-supplied checker program in its own executor.
+// The program is called as `checker input output answer` and answers with its exit code.
+type specialJudge struct {
+	executor Executor
+}
+
+func newSpecialJudge(name string, spec CheckerSpec) (*specialJudge, error) {
+	if spec.Code == "" {
+		return nil, fmt.Errorf("special judge has no checker program")
+	}
+	executor, err := prepareExecutor(name, spec.Language, spec.Code, checkerLimits)
+	if err != nil {
+		return nil, fmt.Errorf("checker: %v", err)
+	}
+	if compile := executor.Compile(); compile.Err != nil || compile.ExitCode != 0 {
+		executor.Cleanup()
+		_, message := compileFailure(compile)
+		return nil, fmt.Errorf("checker: %s", message)
+	}
+	return &specialJudge{executor: executor}, nil
+}
+
+func (j *specialJudge) Check(input, expected, actual string) (Verdict, string) {
+	files := map[string]string{
+		judgeInputFile:  input,
+		judgeOutputFile: actual,
+		judgeAnswerFile: expected,
+	}
+	if err := j.executor.WriteFiles(files); err != nil {
+		return VerdictInternalError, fmt.Sprintf("checker: %v", err)
+	}
+
+	run := j.executor.Run(checkerLimits, nil, fmt.Sprintf("ARGS=%s %s %s", judgeInputFile, judgeOutputFile, judgeAnswerFile))
+	message := strings.TrimSpace(run.Stderr + "\n" + run.Stdout)
+	if run.Err != nil {
+		return VerdictInternalError, fmt.Sprintf("checker: %v", run.Err)
+	}
+	switch run.ExitCode {
+	case judgeExitOK:
+		return VerdictAccepted, message
+	case judgeExitWrongAnswer, judgeExitPresentErr:
+		return VerdictWrongAnswer, message
+	}
+	return VerdictInternalError, fmt.Sprintf("checker failed (exit code %d): %s", run.ExitCode, message)
+}
+
+// Close removes the checker's executor
+func (j *specialJudge) Close() error {
+	j.executor.Cleanup()
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+type checkerTest struct {
+	name     string
+	input    string
+	expected string
+	actual   string
+	want     Verdict
+}
+
+func runCheckerTests(t *testing.T, checker Checker, tests []checkerTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, message := checker.Check(tt.input, tt.expected, tt.actual); got != tt.want {
+				t.Errorf("Check = %q (%s), want %q", got, message, tt.want)
+			}
+		})
+	}
+}
+
+func TestExactChecker(t *testing.T) {
+	runCheckerTests(t, exactChecker{}, []checkerTest{
+		{name: "equal", expected: "1 2\n3\n", actual: "1 2\n3\n", want: VerdictAccepted},
+		{name: "surrounding whitespace", expected: "1 2\n3\n", actual: "\n1 2\n3", want: VerdictAccepted},
+		{name: "inner whitespace", expected: "1 2\n3\n", actual: "1  2\n3\n", want: VerdictWrongAnswer},
+		{name: "different", expected: "1 2\n3\n", actual: "1 2\n4\n", want: VerdictWrongAnswer},
+	})
+}
+
+func TestTokenChecker(t *testing.T) {
+	runCheckerTests(t, tokenChecker{}, []checkerTest{
+		{name: "equal", expected: "1 2 3\n", actual: "1 2 3\n", want: VerdictAccepted},
+		{name: "other separators", expected: "1 2 3\n", actual: "1\n2\t\t3", want: VerdictAccepted},
+		{name: "empty", expected: "\n", actual: "", want: VerdictAccepted},
+		{name: "different token", expected: "1 2 3\n", actual: "1 2 4\n", want: VerdictWrongAnswer},
+		{name: "missing token", expected: "1 2 3\n", actual: "1 2\n", want: VerdictWrongAnswer},
+		{name: "extra token", expected: "1 2 3\n", actual: "1 2 3 4\n", want: VerdictWrongAnswer},
+		{name: "numbers compared as text", expected: "1.0\n", actual: "1\n", want: VerdictWrongAnswer},
+	})
+}
+
+func TestFloatChecker(t *testing.T) {
+	runCheckerTests(t, newFloatChecker(CheckerSpec{Mode: CheckerFloat}), []checkerTest{
+		{name: "equal", expected: "0.5 2\n", actual: "0.5 2\n", want: VerdictAccepted},
+		{name: "other format", expected: "0.5 2\n", actual: "5e-1 2.000\n", want: VerdictAccepted},
+		{name: "within the default epsilon", expected: "3.141592\n", actual: "3.1415925\n", want: VerdictAccepted},
+		{name: "outside the default epsilon", expected: "3.141592\n", actual: "3.1416\n", want: VerdictWrongAnswer},
+		{name: "relative epsilon for large numbers", expected: "1000000000\n", actual: "1000000500\n", want: VerdictAccepted},
+		{name: "words compared exactly", expected: "YES 1\n", actual: "yes 1\n", want: VerdictWrongAnswer},
+		{name: "equal infinities", expected: "inf -inf\n", actual: "+Inf -Inf\n", want: VerdictAccepted},
+		{name: "opposite infinities", expected: "inf\n", actual: "-inf\n", want: VerdictWrongAnswer},
+		{name: "infinity for a number", expected: "1e308\n", actual: "inf\n", want: VerdictWrongAnswer},
+		{name: "NaN", expected: "nan\n", actual: "NaN\n", want: VerdictAccepted},
+		{name: "NaN for a number", expected: "1\n", actual: "nan\n", want: VerdictWrongAnswer},
+		{name: "missing number", expected: "1 2\n", actual: "1\n", want: VerdictWrongAnswer},
+	})
+
+	t.Run("absolute epsilon only", func(t *testing.T) {
+		runCheckerTests(t, newFloatChecker(CheckerSpec{Mode: CheckerFloat, AbsEpsilon: 0.01}), []checkerTest{
+			{name: "within", expected: "1.00\n", actual: "1.009\n", want: VerdictAccepted},
+			{name: "outside", expected: "1.00\n", actual: "1.02\n", want: VerdictWrongAnswer},
+			{name: "no relative epsilon", expected: "1000000\n", actual: "1000001\n", want: VerdictWrongAnswer},
+		})
+	})
+	t.Run("relative epsilon only", func(t *testing.T) {
+		runCheckerTests(t, newFloatChecker(CheckerSpec{Mode: CheckerFloat, RelEpsilon: 1e-3}), []checkerTest{
+			{name: "within", expected: "1000000\n", actual: "1000999\n", want: VerdictAccepted},
+			{name: "outside", expected: "1000000\n", actual: "1001001\n", want: VerdictWrongAnswer},
+			{name: "no absolute epsilon near 0", expected: "0\n", actual: "0.0000001\n", want: VerdictWrongAnswer},
+		})
+	})
+}
+
+func TestFloatCheckerClose(t *testing.T) {
+	c := newFloatChecker(CheckerSpec{AbsEpsilon: 1e-9})
+	if !c.close(math.Inf(1), math.Inf(1)) || !c.close(math.Inf(-1), math.Inf(-1)) {
+		t.Error("equal infinities are not close")
+	}
+	if c.close(math.Inf(1), math.Inf(-1)) || c.close(math.Inf(1), math.MaxFloat64) {
+		t.Error("infinity is close to a different value")
+	}
+	if !c.close(math.NaN(), math.NaN()) || c.close(math.NaN(), 0) {
+		t.Error("NaN is only close to NaN")
+	}
+}
+
+func TestUnorderedChecker(t *testing.T) {
+	runCheckerTests(t, unorderedChecker{}, []checkerTest{
+		{name: "same order", expected: "a b\nc\n", actual: "a b\nc\n", want: VerdictAccepted},
+		{name: "other order", expected: "a b\nc\n", actual: "c\na  b\n\n", want: VerdictAccepted},
+		{name: "missing line", expected: "a\nb\n", actual: "a\n", want: VerdictWrongAnswer},
+		{name: "repeated line", expected: "a\nb\n", actual: "a\na\n", want: VerdictWrongAnswer},
+	})
+}
+
+// The special judge of the tests accepts any proper divisor of the number in the input, and
+// "none" when the answer file says there is none
+const divisorJudge = `read n < "$1"
+read answer < "$3"
+if [ "$n" -le 0 ]; then
+	echo "bad test: $n" >&2
+	exit 3
+fi
+read d < "$2" || { echo "empty output" >&2; exit 2; }
+if [ "$answer" = none ]; then
+	[ "$d" = none ] && exit 0
+	echo "$n has no proper divisor" >&2
+	exit 1
+fi
+case "$d" in
+'' | *[!0-9]*) echo "not a number: $d" >&2; exit 2 ;;
+esac
+if [ "$d" -gt 1 ] && [ "$d" -lt "$n" ] && [ $((n % d)) -eq 0 ]; then
+	echo "ok, $d divides $n"
+	exit 0
+fi
+echo "$d is not a proper divisor of $n" >&2
+exit 1
+`
+
+func TestSpecialJudge(t *testing.T) {
+	useLocalExecutor(t)
+	judge, err := newSpecialJudge("checker-test", CheckerSpec{Mode: CheckerSpecial, Language: testLanguage, Code: divisorJudge})
+	if err != nil {
+		t.Fatalf("newSpecialJudge: %v", err)
+	}
+	defer judge.Close()
+
+	runCheckerTests(t, judge, []checkerTest{
+		{name: "the expected answer", input: "12\n", expected: "2\n", actual: "2\n", want: VerdictAccepted},
+		{name: "another valid answer", input: "12\n", expected: "2\n", actual: "6\n", want: VerdictAccepted},
+		{name: "wrong answer", input: "12\n", expected: "2\n", actual: "5\n", want: VerdictWrongAnswer},
+		{name: "no answer", input: "13\n", expected: "none\n", actual: "none\n", want: VerdictAccepted},
+		{name: "answer when there is none", input: "13\n", expected: "none\n", actual: "13\n", want: VerdictWrongAnswer},
+		{name: "presentation error", input: "12\n", expected: "2\n", actual: "two\n", want: VerdictWrongAnswer},
+		{name: "empty output", input: "12\n", expected: "2\n", actual: "", want: VerdictWrongAnswer},
+		{name: "checker failure", input: "0\n", expected: "none\n", actual: "none\n", want: VerdictInternalError},
+	})
+
+	t.Run("message", func(t *testing.T) {
+		if _, message := judge.Check("12\n", "2\n", "5\n"); !strings.Contains(message, "5 is not a proper divisor of 12") {
+			t.Errorf("the checker's message is lost: %q", message)
+		}
+	})
+}
+
+func TestSpecialJudgeInvalid(t *testing.T) {
+	useLocalExecutor(t)
+	if _, err := newSpecialJudge("checker-test", CheckerSpec{Mode: CheckerSpecial, Language: testLanguage}); err == nil {
+		t.Error("a special judge without a program was accepted")
+	}
+	if _, err := newSpecialJudge("checker-test", CheckerSpec{Mode: CheckerSpecial, Language: "brainfuck", Code: "+"}); err == nil {
+		t.Error("a special judge in an unknown language was accepted")
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/joho/godotenv"
+)
+
+// db stores the submissions; nil when DATABASE_URL is not set (playground-only worker)
+var db *pgxpool.Pool
+
+func connectToDB() {
+	// Same .env as the API (DATABASE_URL)
+	if err := godotenv.Load(); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: failed to load .env file: %v", err)
+	}
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		log.Println("Warning: DATABASE_URL not set, submissions will not be stored")
+		return
+	}
+
+	var err error
+	db, err = pgxpool.Connect(ctx, databaseURL)
+	if err != nil {
+		log.Fatalf("Unable to connect to database: %v\n", err)
+	}
+}
+
+// recordSubmission stores the result of a submission, once per job:
+// create_submission does nothing if the job was already stored, so a failed job can run again.
+// The user earns the part of result.Score above their best score on the problem.
+func recordSubmission(job Job, result JobResult) error {
+	if db == nil || job.Mode != ModeSubmit || job.UserID == "" || job.ProblemID == "" {
+		return nil
+	}
+
+	// The full breakdown (per-test results, exit code, stderr) as the user got it, without the
+	// hidden tests an admin saw: other users who solved the problem can read it
+	result = redactHidden(job, result)
+	details, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("marshaling details: %v", err)
+	}
+
+	cpuTimeMs, memoryKB := usageColumns(result)
+	_, err = db.Exec(
+		ctx,
+		"CALL create_submission($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)",
+		job.ID, job.UserID, job.ProblemID, result.Status == "accept", job.Language, result.ExecTime, result.Output, result.Verdict,
+		job.Code, languages[job.Language].Version, string(details), result.Score, cpuTimeMs, memoryKB,
+	)
+	if err != nil {
+		return err
+	}
+	log.Printf("Submission stored for user %s on problem %s (job %s)", job.UserID, job.ProblemID, job.ID)
+	return nil
+}
+
+// usageColumns are the cpu_time_ms and memory_kb of a submission, NULL when the executor did not
+// report the usage of every test (a measured 0 is stored as 0)
+func usageColumns(result JobResult) (cpuTimeMs, memoryKB *int64) {
+	if !result.measured {
+		return nil, nil
+	}
+	return &result.CPUTime, &result.MemoryKB
+}
+
+// Suggested time limit of a problem: this many times the slowest test of the reference solution
+const suggestedTimeLimitFactor = 3
+
+// slowestTestMs is the time of the slowest test of the reference solution, as the time limit
+// counts it: its CPU time, or the wall time of the tests the executor did not measure
+func slowestTestMs(tests []TestCaseResult) int64 {
+	var slowest int64
+	for _, tc := range tests {
+		ms := tc.TimeMs
+		if tc.measured {
+			ms = tc.CPUTimeMs
+		}
+		if ms > slowest {
+			slowest = ms
+		}
+	}
+	return slowest
+}
+
+// recordValidation stores the outcome of running a problem's reference solution on its tests:
+// the problem is published only if it passed all of them within the limits. Outcomes of
+// validations that were superseded by a newer one are ignored.
+func recordValidation(job Job, result JobResult) {
+	if db == nil || job.Mode != ModeValidate || job.ProblemID == "" {
+		return
+	}
+
+	valid := result.Verdict == VerdictAccepted
+	status := "valid"
+	if !valid {
+		status = "invalid"
+	}
+
+	slowest := slowestTestMs(result.Tests)
+	// problem.timelimit is in whole seconds
+	suggested := (slowest*suggestedTimeLimitFactor + 999) / 1000
+	if suggested < 1 {
+		suggested = 1
+	}
+
+	message := result.Output
+	if result.Error != "" {
+		message = strings.TrimSpace(result.Error + "\n" + message)
+	}
+
+	tag, err := db.Exec(ctx, `
+		UPDATE problem
+		SET validation_status = $1, validation_message = $2, reference_time_ms = $3,
+			suggested_timelimit = $4, published = $5
+		WHERE problem_id = $6 AND validation_job_id = $7`,
+		status, message, slowest, suggested, valid, job.ProblemID, job.ID)
+	if err != nil {
+		log.Printf("Error storing validation of problem %s (job %s): %v", job.ProblemID, job.ID, err)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		log.Printf("Validation job %s of problem %s was superseded, ignoring it", job.ID, job.ProblemID)
+		return
+	}
+	log.Printf("Problem %s validated: %s (%s, slowest test %d ms)", job.ProblemID, status, result.Verdict, slowest)
+}
+
+// storeGeneratedTests replaces the generated test cases of a problem (those with generator_args)
+// in a single transaction. The reference solution produced them within the limits, so a problem
+// that was only waiting for test cases is now valid.
+func storeGeneratedTests(job Job, generated []generatedTest, tests []TestCaseResult) error {
+	if db == nil {
+		return fmt.Errorf("DATABASE_URL is not set")
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM testcases WHERE problem_id = $1 AND generator_args IS NOT NULL`, job.ProblemID); err != nil {
+		return err
+	}
+	var position int
+	if err := tx.QueryRow(ctx, `SELECT COALESCE(MAX(position), 0) FROM testcases WHERE problem_id = $1`, job.ProblemID).Scan(&position); err != nil {
+		return err
+	}
+	for i, test := range generated {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO testcases (problem_id, tin, tout, position, generator_args)
+			VALUES ($1, $2, $3, $4, $5)`,
+			job.ProblemID, test.Input, test.Output, position+i+1, test.Args); err != nil {
+			return err
+		}
+	}
+
+	slowest := slowestTestMs(tests)
+	if _, err := tx.Exec(ctx, `
+		UPDATE problem
+		SET published = true, validation_status = 'valid', validation_message = $1,
+			reference_time_ms = $2, suggested_timelimit = GREATEST(($2 * $3 + 999) / 1000, 1)
+		WHERE problem_id = $4 AND validation_status = 'pending' AND validation_job_id IS NULL`,
+		fmt.Sprintf("Generated %d tests", len(generated)), slowest, suggestedTimeLimitFactor, job.ProblemID); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	log.Printf("Stored %d generated tests for problem %s (job %s)", len(generated), job.ProblemID, job.ID)
+	return nil
+}
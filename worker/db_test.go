@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func TestUsageColumns(t *testing.T) {
+	measured := TestCaseResult{CPUTimeMs: 0, MemoryKB: 0, measured: true}
+	unknown := TestCaseResult{}
+	tests := []struct {
+		name  string
+		tests []TestCaseResult
+		null  bool
+	}{
+		{"measured zero", []TestCaseResult{measured, measured}, false},
+		{"one test not measured", []TestCaseResult{measured, unknown}, true},
+		{"no tests", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var res JobResult
+			res.CPUTime, res.MemoryKB, res.measured = peakUsage(tt.tests)
+			cpuTimeMs, memoryKB := usageColumns(res)
+			if tt.null {
+				if cpuTimeMs != nil || memoryKB != nil {
+					t.Errorf("got %d ms and %d KB, want NULL", *cpuTimeMs, *memoryKB)
+				}
+				return
+			}
+			if cpuTimeMs == nil || memoryKB == nil || *cpuTimeMs != 0 || *memoryKB != 0 {
+				t.Errorf("got %v and %v, want 0 ms and 0 KB", cpuTimeMs, memoryKB)
+			}
+		})
+	}
+}
+
+func TestSlowestTestMs(t *testing.T) {
+	tests := []TestCaseResult{
+		{TimeMs: 900, CPUTimeMs: 120, measured: true}, // mostly the executor's overhead
+		{TimeMs: 800, CPUTimeMs: 300, measured: true},
+	}
+	if got := slowestTestMs(tests); got != 300 {
+		t.Errorf("measured tests: got %d ms, want the most CPU time (300 ms)", got)
+	}
+	tests = append(tests, TestCaseResult{TimeMs: 500})
+	if got := slowestTestMs(tests); got != 500 {
+		t.Errorf("with a test not measured: got %d ms, want its wall time (500 ms)", got)
+	}
+	if got := slowestTestMs(nil); got != 0 {
+		t.Errorf("no tests: got %d ms", got)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// Event types published on a job's channel
+const (
+	EventStage  = "stage"  // the job reached a new stage (see status.go)
+	EventTest   = "test"   // a test case finished
+	EventResult = "result" // the final result is stored, last event of a job
+)
+
+// JobEvent is published on job:<id>:events as the job progresses (streamed by GET /result/{id}/stream)
+type JobEvent struct {
+	Type   string          `json:"type"`
+	JobID  string          `json:"job_id"`
+	Stage  string          `json:"stage,omitempty"`
+	Test   int             `json:"test,omitempty"`
+	Total  int             `json:"total,omitempty"`
+	Case   *TestCaseResult `json:"case,omitempty"`   // EventTest
+	Result *JobResult      `json:"result,omitempty"` // EventResult
+}
+
+// eventChannel is the pub/sub channel of a job
+func eventChannel(jobID string) string {
+	return "job:" + jobID + ":events"
+}
+
+// publishEvent sends an event to whoever is streaming the job (nobody, most of the time)
+func publishEvent(event JobEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error marshaling %s event of job %s: %v", event.Type, event.JobID, err)
+		return
+	}
+	if err := rdb.Publish(ctx, eventChannel(event.JobID), data).Err(); err != nil {
+		log.Printf("Error publishing %s event of job %s: %v", event.Type, event.JobID, err)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Executor backends, chosen with EXECUTOR_BACKEND
+const (
+	backendDocker = "docker" // one executor container per program (default)
+	backendLocal  = "local"  // processes on the worker host, for development and tests
+)
+
+// Executor runs one program: it is prepared once, compiled once and then run for every test
+type Executor interface {
+	// Prepare sets up the sandbox for code written in lang, to be run under limits
+	Prepare(name string, lang Language, code string, limits Limits) error
+	// Compile compiles the code (a syntax check for interpreted languages).
+	// Code the compiler rejects exits with exitCodeCompileError.
+	Compile() runOutcome
+	// Run runs the program once with stdin (nil for no input) and extra NAME=value environment variables.
+	// The outcome has the program's CPU time and peak memory when the backend measures them.
+	Run(limits Limits, stdin io.Reader, env ...string) runOutcome
+	// Interact runs the program like Run, but writes its stdout to stdout as it is produced
+	// (interactive problems); the outcome has no Stdout
+	Interact(limits Limits, stdin io.Reader, stdout io.Writer, env ...string) runOutcome
+	// WriteFiles stores files (content by path, relative to the directory the program runs in)
+	// in one go
+	WriteFiles(files map[string]string) error
+	// Sibling prepares more code in the same language, in a work directory of its own but in the
+	// same sandbox, under the same limits. It is removed by this executor's Cleanup.
+	Sibling(name, code string) (Executor, error)
+	// Cleanup removes everything Prepare set up
+	Cleanup()
+}
+
+var errUnsupportedLanguage = errors.New("unsupported language")
+
+// newExecutor returns an executor of the backend configured in EXECUTOR_BACKEND
+func newExecutor() (Executor, error) {
+	switch backend := os.Getenv("EXECUTOR_BACKEND"); backend {
+	case "", backendDocker:
+		return &dockerExecutor{}, nil
+	case backendLocal:
+		return &localExecutor{}, nil
+	default:
+		return nil, fmt.Errorf("unknown executor backend: %s", backend)
+	}
+}
+
+// prepareExecutor prepares an executor for code written in language
+func prepareExecutor(name, language, code string, limits Limits) (Executor, error) {
+	lang, ok := languages[language]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errUnsupportedLanguage, language)
+	}
+	executor, err := newExecutor()
+	if err != nil {
+		return nil, err
+	}
+	if err := executor.Prepare(name, lang, code, limits); err != nil {
+		executor.Cleanup()
+		return nil, err
+	}
+	return executor, nil
+}
+
+// compileFailure tells why Compile failed: a compilation error or a judge problem
+func compileFailure(compile runOutcome) (Verdict, string) {
+	verdict := VerdictCompilationError
+	message := "Compilation failed"
+	if errors.Is(compile.Err, errWallTimeExceeded) {
+		message = "Compilation timed out"
+	} else if compile.Err != nil {
+		verdict = VerdictInternalError
+		message = fmt.Sprintf("Failed to prepare code: %v", compile.Err)
+	} else if compile.ExitCode != exitCodeCompileError {
+		verdict = VerdictInternalError
+		message = fmt.Sprintf("Failed to prepare code (exit code %d)", compile.ExitCode)
+	}
+	return verdict, strings.TrimSpace(message + "\n" + compile.Stderr)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Directory the executor scripts compile and run the code in
+const dockerWorkDir = "/tmp/work"
+
+// dockerExecutor is a detached executor container holding one program, reused for compiling and every run.
+// Siblings are other programs in the same container, in work directories of their own.
+type dockerExecutor struct {
+	containerID string
+	execPath    string
+	sourceFile  string
+	workDir     string // "" for dockerWorkDir
+	sibling     bool   // the container belongs to another executor
+}
+
+// Prepare starts the executor container of lang with its sandbox profile (no network, read-only
+// root filesystem, no capabilities) and copies the code into its work directory
+func (d *dockerExecutor) Prepare(name string, lang Language, code string, limits Limits) error {
+	d.containerID = fmt.Sprintf("code-exec-%s", name)
+	d.execPath = lang.Entrypoint
+	d.sourceFile = lang.SourceFile
+	_ = exec.Command("docker", "rm", "-f", d.containerID).Run() // best‐effort cleanup
+
+	dockerRunArgs := []string{
+		"run", "-d",
+		"--name", d.containerID,
+		"--cpus=0.5", fmt.Sprintf("--pids-limit=%d", maxProcesses),
+	}
+	dockerRunArgs = append(dockerRunArgs, sandboxArgs(lang.Sandbox)...)
+	dockerRunArgs = append(dockerRunArgs, limits.dockerArgs()...)
+	dockerRunArgs = append(dockerRunArgs, limits.envArgs()...)
+	dockerRunArgs = append(dockerRunArgs, languageEnvArgs(lang)...)
+	dockerRunArgs = append(dockerRunArgs, lang.Image)
+	if err := exec.Command("docker", dockerRunArgs...).Run(); err != nil {
+		return fmt.Errorf("failed to start executor container: %v", err)
+	}
+	return d.copyFiles(map[string]string{lang.SourceFile: code})
+}
+
+// Compile runs PHASE=compile (compile, or a syntax check for interpreted languages)
+func (d *dockerExecutor) Compile() runOutcome {
+	return runLimited(Limits{WallTime: compileTimeout}, nil, nil, "exec", "-e", "PHASE=compile", "-e", "WORK_DIR="+d.dir(), d.containerID, d.execPath)
+}
+
+// Run runs PHASE=run with stdin (nil for no input) and extra environment variables
+func (d *dockerExecutor) Run(limits Limits, stdin io.Reader, env ...string) runOutcome {
+	return withUsage(runLimited(limits, stdin, nil, d.execArgs(stdin != nil, env)...))
+}
+
+// Interact runs PHASE=run with INTERACTIVE=1, so the executor script passes stdin and stdout
+// through to the program instead of reading all the input first
+func (d *dockerExecutor) Interact(limits Limits, stdin io.Reader, stdout io.Writer, env ...string) runOutcome {
+	return withUsage(runLimited(limits, stdin, stdout, d.execArgs(true, append(env, "INTERACTIVE=1"))...))
+}
+
+// Prefix of the line the executor scripts end the stderr of a run with, reporting the program's
+// resource usage (from getrusage): "EXECUTOR_USAGE <user CPU s> <system CPU s> <peak RSS KB>"
+const usageMarker = "EXECUTOR_USAGE "
+
+// withUsage takes the usage line out of the stderr of a run and fills in its CPU time and memory
+func withUsage(o runOutcome) runOutcome {
+	i := strings.LastIndex(o.Stderr, usageMarker)
+	if i < 0 {
+		return o
+	}
+	fields := strings.Fields(o.Stderr[i+len(usageMarker):])
+	if len(fields) != 3 {
+		return o
+	}
+	user, errUser := strconv.ParseFloat(fields[0], 64)
+	system, errSystem := strconv.ParseFloat(fields[1], 64)
+	memory, errMemory := strconv.ParseInt(fields[2], 10, 64)
+	if errUser != nil || errSystem != nil || errMemory != nil {
+		return o
+	}
+	o.Stderr = o.Stderr[:i]
+	o.CPUTime = time.Duration(math.Round((user + system) * float64(time.Second)))
+	o.MemoryKB = memory
+	o.UsageMeasured = true
+	return o
+}
+
+// execArgs builds the `docker exec` arguments of a run
+func (d *dockerExecutor) execArgs(withStdin bool, env []string) []string {
+	args := []string{"exec"}
+	if withStdin {
+		args = append(args, "-i") // Add -i flag for interactive stdin
+	}
+	args = append(args, "-e", "PHASE=run", "-e", "WORK_DIR="+d.dir())
+	for _, e := range env {
+		args = append(args, "-e", e)
+	}
+	return append(args, d.containerID, d.execPath)
+}
+
+// WriteFiles stores files inside the container's work directory, with a single tar stream
+func (d *dockerExecutor) WriteFiles(files map[string]string) error {
+	return d.copyFiles(files)
+}
+
+// copyFiles writes files (by path relative to the work directory) into the container with a tar
+// stream on the stdin of `tar -x`, so the code never goes through the network. Every executor
+// image must include tar.
+// tar runs as the container's user, who then owns the files.
+func (d *dockerExecutor) copyFiles(files map[string]string) error {
+	archive, err := tarFiles(path.Base(d.dir()), files)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("docker", "exec", "-i", d.containerID, "tar", "-x", "-C", path.Dir(d.dir()))
+	cmd.Stdin = bytes.NewReader(archive)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to copy files into the container: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// tarFiles builds a tar archive holding files under dir, with the directories they are in
+func tarFiles(dir string, files map[string]string) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	now := time.Now().Truncate(time.Second) // tar rounds to seconds, which may be in the future
+	written := make(map[string]bool)
+	var writeDir func(name string) error
+	writeDir = func(name string) error {
+		if name == "." || name == "/" || written[name] {
+			return nil
+		}
+		if err := writeDir(path.Dir(name)); err != nil {
+			return err
+		}
+		written[name] = true
+		return tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: name + "/", Mode: 0o777, ModTime: now})
+	}
+	for _, name := range names {
+		full := path.Join(dir, name)
+		if err := writeDir(path.Dir(full)); err != nil {
+			return nil, err
+		}
+		content := files[name]
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: full, Mode: 0o644, Size: int64(len(content)), ModTime: now}); err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(tw, content); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// dir is the work directory of the program
+func (d *dockerExecutor) dir() string {
+	if d.workDir != "" {
+		return d.workDir
+	}
+	return dockerWorkDir
+}
+
+// Sibling copies code into another work directory of the container
+func (d *dockerExecutor) Sibling(name, code string) (Executor, error) {
+	s := &dockerExecutor{
+		containerID: d.containerID,
+		execPath:    d.execPath,
+		sourceFile:  d.sourceFile,
+		workDir:     dockerWorkDir + "-" + name,
+		sibling:     true,
+	}
+	if err := s.copyFiles(map[string]string{s.sourceFile: code}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Cleanup removes the container
+func (d *dockerExecutor) Cleanup() {
+	if d.containerID != "" && !d.sibling {
+		exec.Command("docker", "rm", "-f", d.containerID).Run()
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWithUsage(t *testing.T) {
+	tests := []struct {
+		name       string
+		stderr     string
+		wantStderr string
+		cpu        time.Duration
+		memoryKB   int64
+		measured   bool
+	}{
+		{"reported", "oops\nEXECUTOR_USAGE 0.25 0.05 2048\n", "oops\n", 300 * time.Millisecond, 2048, true},
+		{"measured zero", "EXECUTOR_USAGE 0.00 0.00 0\n", "", 0, 0, true},
+		{"not reported", "Execution timed out.\n", "Execution timed out.\n", 0, 0, false},
+		{"malformed", "EXECUTOR_USAGE 0.1 x 10\n", "EXECUTOR_USAGE 0.1 x 10\n", 0, 0, false},
+		// The executor reports last: a line the program wrote before is left in its stderr
+		{"forged by the program", "EXECUTOR_USAGE 0 0 1\nEXECUTOR_USAGE 1.5 0.5 4096\n", "EXECUTOR_USAGE 0 0 1\n", 2 * time.Second, 4096, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := withUsage(runOutcome{Stderr: tt.stderr})
+			if o.Stderr != tt.wantStderr {
+				t.Errorf("stderr %q, want %q", o.Stderr, tt.wantStderr)
+			}
+			if o.CPUTime != tt.cpu || o.MemoryKB != tt.memoryKB || o.UsageMeasured != tt.measured {
+				t.Errorf("got %v, %d KB (measured: %v), want %v, %d KB (measured: %v)",
+					o.CPUTime, o.MemoryKB, o.UsageMeasured, tt.cpu, tt.memoryKB, tt.measured)
+			}
+		})
+	}
+}
+
+// failingWriter is the relay of an interaction whose other side hung up
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, syscall.EPIPE
+}
+
+// A program whose output could not be relayed keeps its exit status instead of becoming an IE
+func TestRunLimitedStdoutClosed(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "docker"), []byte("#!/bin/sh\necho answer\nexit $1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	limits := Limits{CPUTime: time.Second, WallTime: 5 * time.Second, MemoryMB: 64}
+	for _, code := range []int{0, 3} {
+		o := runLimited(limits, nil, failingWriter{}, strconv.Itoa(code))
+		if o.Err != nil || o.ExitCode != code {
+			t.Errorf("exit %d: got exit code %d (%v)", code, o.ExitCode, o.Err)
+		}
+	}
+}
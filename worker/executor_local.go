@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// localExecutor runs the program as processes on the worker host, in a temporary work directory.
+// CPU time is limited with rlimits; network isolation (namespaces) and the memory limit (cgroup v2)
+// are applied where the host allows them. The language toolchains must be installed on the host.
+type localExecutor struct {
+	dir      string
+	lang     Language
+	cgroup   string // cgroup the runs are placed in, "" when memory is not limited
+	siblings []string
+	sibling  bool // the cgroup belongs to another executor
+}
+
+// Prepare creates the work directory with the source file and the cgroup for the runs
+func (e *localExecutor) Prepare(name string, lang Language, code string, limits Limits) error {
+	dir, err := os.MkdirTemp("", "code-exec-"+name+"-")
+	if err != nil {
+		return fmt.Errorf("failed to create work directory: %v", err)
+	}
+	e.dir, e.lang = dir, lang
+
+	if err := e.WriteFiles(map[string]string{lang.SourceFile: code}); err != nil {
+		return err
+	}
+	e.cgroup = createCgroup(filepath.Base(dir), limits)
+	return nil
+}
+
+// Compile runs the language's compile command, reporting rejected code like the executor scripts do
+func (e *localExecutor) Compile() runOutcome {
+	if e.lang.Compile == "" {
+		return runOutcome{}
+	}
+	compile := e.runScript(Limits{WallTime: compileTimeout}, nil, nil, e.lang.Compile, languageEnv(e.lang), "")
+	if compile.Err == nil && compile.ExitCode != 0 {
+		compile.Stderr = "Compilation error:\n" + compile.Stdout + compile.Stderr
+		compile.ExitCode = exitCodeCompileError
+	}
+	return compile
+}
+
+// Run runs the program under the CPU time limit (hard limit one second later, so it gets SIGXCPU)
+func (e *localExecutor) Run(limits Limits, stdin io.Reader, env ...string) runOutcome {
+	return e.Interact(limits, stdin, nil, env...)
+}
+
+// Interact runs the program like Run, writing its stdout to stdout (kept in the outcome if nil).
+// $ARGS are split into the program's arguments, but not globbed.
+func (e *localExecutor) Interact(limits Limits, stdin io.Reader, stdout io.Writer, env ...string) runOutcome {
+	cpu := limits.cpuSeconds()
+	script := fmt.Sprintf("set -f; ulimit -S -t %d; ulimit -H -t %d; exec %s $ARGS", cpu, cpu+1, e.lang.Run)
+	env = append(append(limits.env(), languageEnv(e.lang)...), env...)
+	return e.runScript(limits, stdin, stdout, script, env, e.cgroup)
+}
+
+// WriteFiles stores files inside the work directory
+func (e *localExecutor) WriteFiles(files map[string]string) error {
+	for name, content := range files {
+		path := filepath.Join(e.dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to write %s: %v", path, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// Sibling writes code in another work directory, next to this one, whose runs share the cgroup
+func (e *localExecutor) Sibling(name, code string) (Executor, error) {
+	s := &localExecutor{dir: e.dir + "-" + name, lang: e.lang, cgroup: e.cgroup, sibling: true}
+	if err := os.Mkdir(s.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %v", err)
+	}
+	e.siblings = append(e.siblings, s.dir)
+	if err := s.WriteFiles(map[string]string{e.lang.SourceFile: code}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Cleanup removes the cgroup and the work directories
+func (e *localExecutor) Cleanup() {
+	if e.sibling {
+		return
+	}
+	removeCgroup(e.cgroup)
+	for _, dir := range append(e.siblings, e.dir) {
+		if dir != "" {
+			os.RemoveAll(dir)
+		}
+	}
+}
+
+// runScript runs script with sh in the work directory (inside cgroup, if set),
+// killing its whole process group if it outlives the wall time limit.
+// Its stdout is kept in the outcome, or written to stdout instead if that is not nil.
+func (e *localExecutor) runScript(l Limits, stdin io.Reader, stdout io.Writer, script string, env []string, cgroup string) runOutcome {
+	if cgroup != "" {
+		// The shell joins the cgroup itself, so the program is limited from its first instruction
+		script = `echo $$ > "$CGROUP_PROCS" && ` + script
+		env = append(env, "CGROUP_PROCS="+filepath.Join(cgroup, "cgroup.procs"))
+	}
+
+	var output, stderr cappedBuffer
+	cmd := exec.Command("sh", "-c", script)
+	cmd.Dir = e.dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = stdin
+	cmd.Stdout = &output
+	if stdout != nil {
+		cmd.Stdout = stdout
+	}
+	cmd.Stderr = &stderr
+	cmd.SysProcAttr = localSysProcAttr()
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return runOutcome{ExitCode: -1, Err: fmt.Errorf("failed to start %s: %v", e.lang.ID, err)}
+	}
+	var timedOut atomic.Bool
+	timer := time.AfterFunc(l.WallTime, func() {
+		timedOut.Store(true)
+		killProcessGroup(cmd.Process)
+	})
+	err := cmd.Wait()
+	timer.Stop()
+
+	outcome := runOutcome{
+		Stdout:         output.String(),
+		Stderr:         stderr.String(),
+		ExitCode:       exitStatus(cmd.ProcessState),
+		Elapsed:        time.Since(start),
+		CPUTime:        cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime(),
+		OutputOverflow: output.overflow,
+	}
+	outcome.MemoryKB, outcome.UsageMeasured = peakMemoryKB(cmd.ProcessState)
+	if timedOut.Load() {
+		outcome.Err = errWallTimeExceeded
+	} else if outcome.ExitCode < 0 {
+		outcome.Err = err
+	}
+	return outcome
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+// Parent of the per-program cgroups of the local backend (cgroup v2), overridden by LOCAL_CGROUP_ROOT
+const defaultCgroupRoot = "/sys/fs/cgroup/code-exec"
+
+// Namespaces for local runs: no network, private IPC and hostname.
+// No PID namespace: the program would be its init and the kernel would drop its SIGXCPU.
+const localNamespaces = syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+
+var (
+	namespacesOnce sync.Once
+	namespaceAttr  *syscall.SysProcAttr // nil when the host does not let us create namespaces
+
+	cgroupWarning sync.Once
+)
+
+// localSysProcAttr puts local runs in their own process group and, where possible, their own namespaces
+func localSysProcAttr() *syscall.SysProcAttr {
+	namespacesOnce.Do(func() {
+		attr := &syscall.SysProcAttr{Setpgid: true, Cloneflags: localNamespaces}
+		if uid, gid := os.Getuid(), os.Getgid(); uid != 0 {
+			// Unprivileged: namespaces are only allowed inside a user namespace of our own
+			attr.Cloneflags |= syscall.CLONE_NEWUSER
+			attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}}
+			attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}}
+		}
+		probe := exec.Command("true")
+		probe.SysProcAttr = attr
+		if err := probe.Run(); err != nil {
+			log.Printf("Warning: local executor runs without namespaces: %v", err)
+			return
+		}
+		namespaceAttr = attr
+	})
+
+	if namespaceAttr == nil {
+		return &syscall.SysProcAttr{Setpgid: true}
+	}
+	attr := *namespaceAttr
+	return &attr
+}
+
+// createCgroup creates a cgroup limiting memory (no swap) and processes, or returns "" if cgroup v2 is not usable
+func createCgroup(name string, limits Limits) string {
+	root := os.Getenv("LOCAL_CGROUP_ROOT")
+	if root == "" {
+		root = defaultCgroupRoot
+	}
+
+	path := filepath.Join(root, name)
+	err := os.MkdirAll(root, 0755)
+	if err == nil {
+		// Let the per-program cgroups use the memory and pids controllers (best effort, may already be on)
+		os.WriteFile(filepath.Join(root, "cgroup.subtree_control"), []byte("+memory +pids"), 0644)
+		err = os.Mkdir(path, 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(path, "memory.max"), []byte(fmt.Sprint(limits.MemoryMB<<20)), 0644)
+		if err == nil {
+			// Not every kernel accounts swap; the memory limit alone is still worth having
+			os.WriteFile(filepath.Join(path, "memory.swap.max"), []byte("0"), 0644)
+			os.WriteFile(filepath.Join(path, "pids.max"), []byte(fmt.Sprint(maxProcesses)), 0644)
+		} else {
+			os.Remove(path)
+		}
+	}
+	if err != nil {
+		cgroupWarning.Do(func() {
+			log.Printf("Warning: local executor cannot use cgroups, memory limits are not enforced: %v", err)
+		})
+		return ""
+	}
+	return path
+}
+
+// removeCgroup deletes a cgroup made by createCgroup
+func removeCgroup(path string) {
+	if path != "" {
+		os.Remove(path)
+	}
+}
+
+// killProcessGroup kills the program and anything it started
+func killProcessGroup(p *os.Process) {
+	syscall.Kill(-p.Pid, syscall.SIGKILL)
+}
+
+// exitStatus returns the exit code of a finished process, 128+n if signal n killed it (like a shell)
+func exitStatus(state *os.ProcessState) int {
+	if state == nil {
+		return -1
+	}
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return state.ExitCode()
+}
+
+// peakMemoryKB returns the largest resident set of a finished process (and the ones it waited for),
+// and whether the system reported it
+func peakMemoryKB(state *os.ProcessState) (int64, bool) {
+	if usage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		return usage.Maxrss, true // KB on Linux
+	}
+	return 0, false
+}
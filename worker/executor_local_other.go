@@ -0,0 +1,35 @@
+//go:build !linux
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// Outside Linux the local executor only has rlimits: no namespaces and no cgroups
+
+func localSysProcAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+func createCgroup(name string, limits Limits) string {
+	return ""
+}
+
+func removeCgroup(path string) {}
+
+func killProcessGroup(p *os.Process) {
+	p.Kill()
+}
+
+func exitStatus(state *os.ProcessState) int {
+	if state == nil {
+		return -1
+	}
+	return state.ExitCode()
+}
+
+func peakMemoryKB(state *os.ProcessState) (int64, bool) {
+	return 0, false
+}
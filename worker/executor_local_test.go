@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Language of the programs the tests run with the local executor: shell scripts
+const testLanguage = "sh"
+
+// useLocalExecutor makes prepareExecutor use the local backend, with sh as the only language.
+// cgroups are left out so the tests behave the same whatever the host allows.
+func useLocalExecutor(t *testing.T) {
+	t.Helper()
+	t.Setenv("EXECUTOR_BACKEND", backendLocal)
+	t.Setenv("LOCAL_CGROUP_ROOT", "/proc/no-cgroups-in-tests")
+
+	saved := languages
+	languages = map[string]Language{
+		testLanguage: {ID: testLanguage, Name: "Shell", SourceFile: "main.sh", Run: "sh main.sh"},
+	}
+	t.Cleanup(func() { languages = saved })
+}
+
+// prepareScript prepares a local executor holding script, removed when the test ends
+func prepareScript(t *testing.T, name, script string, limits Limits) Executor {
+	t.Helper()
+	executor, err := prepareExecutor(name, testLanguage, script, limits)
+	if err != nil {
+		t.Fatalf("prepareExecutor: %v", err)
+	}
+	t.Cleanup(executor.Cleanup)
+	if compile := executor.Compile(); compile.Err != nil || compile.ExitCode != 0 {
+		t.Fatalf("compile: %v (exit code %d): %s", compile.Err, compile.ExitCode, compile.Stderr)
+	}
+	return executor
+}
+
+func TestLocalExecutorRun(t *testing.T) {
+	useLocalExecutor(t)
+	limits := Limits{CPUTime: time.Second, WallTime: 5 * time.Second, MemoryMB: 64}
+	executor := prepareScript(t, "run", "read a b\necho $((a + b))\necho oops >&2\ncat judge/extra.txt\n", limits)
+	if err := executor.WriteFiles(map[string]string{"judge/extra.txt": "extra\n"}); err != nil {
+		t.Fatalf("WriteFiles: %v", err)
+	}
+
+	run := executor.Run(limits, strings.NewReader("2 3\n"))
+	if run.Err != nil || run.ExitCode != 0 {
+		t.Fatalf("run failed: %v (exit code %d): %s", run.Err, run.ExitCode, run.Stderr)
+	}
+	if run.Stdout != "5\nextra\n" || run.Stderr != "oops\n" {
+		t.Errorf("got stdout %q and stderr %q", run.Stdout, run.Stderr)
+	}
+	if !run.UsageMeasured || run.MemoryKB <= 0 {
+		t.Errorf("peak memory not measured: %d KB (measured: %v)", run.MemoryKB, run.UsageMeasured)
+	}
+}
+
+func TestLocalExecutorSibling(t *testing.T) {
+	useLocalExecutor(t)
+	limits := Limits{CPUTime: time.Second, WallTime: 5 * time.Second, MemoryMB: 64}
+	executor, err := prepareExecutor("sibling", testLanguage, "echo first > out.txt\ncat out.txt\n", limits)
+	if err != nil {
+		t.Fatalf("prepareExecutor: %v", err)
+	}
+	sibling, err := executor.Sibling("second", "cat out.txt 2>/dev/null || echo second\n")
+	if err != nil {
+		executor.Cleanup()
+		t.Fatalf("Sibling: %v", err)
+	}
+
+	// Each program runs its own code in its own work directory
+	if run := executor.Run(limits, nil); run.Stdout != "first\n" {
+		t.Errorf("first program: got %q (%s)", run.Stdout, run.Stderr)
+	}
+	if run := sibling.Run(limits, nil); run.Stdout != "second\n" {
+		t.Errorf("sibling: got %q (%s)", run.Stdout, run.Stderr)
+	}
+
+	// The sibling's Cleanup leaves the sandbox alone, the first program's removes both
+	dirs := []string{executor.(*localExecutor).dir, sibling.(*localExecutor).dir}
+	sibling.Cleanup()
+	if run := executor.Run(limits, nil); run.ExitCode != 0 {
+		t.Errorf("first program after the sibling's cleanup: exit code %d (%s)", run.ExitCode, run.Stderr)
+	}
+	executor.Cleanup()
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("work directory %s was not removed: %v", dir, err)
+		}
+	}
+}
+
+// Arguments (generator arguments, the files of a checker) reach the program as words, unexpanded
+func TestLocalExecutorArgs(t *testing.T) {
+	useLocalExecutor(t)
+	limits := Limits{CPUTime: time.Second, WallTime: 5 * time.Second, MemoryMB: 64}
+	executor := prepareScript(t, "args", "for arg in \"$@\"; do echo \"[$arg]\"; done\n", limits)
+
+	run := executor.Run(limits, nil, "ARGS=7 $(echo evaluated) * `id` a;b")
+	if want := "[7]\n[$(echo]\n[evaluated)]\n[*]\n[`id`]\n[a;b]\n"; run.Stdout != want {
+		t.Errorf("got %q, want %q (%s)", run.Stdout, want, run.Stderr)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Program is a helper program of a problem: a test generator or an input validator
+type Program struct {
+	Language string `json:"language"`
+	Code     string `json:"code"`
+}
+
+// Largest number of tests a generation job may produce
+const maxGeneratedTests = 200
+
+// generatedTest is one test produced by a generation job
+type generatedTest struct {
+	Args   string
+	Input  string
+	Output string
+}
+
+// generateTests runs a generation job (ModeGenerate): for each entry of GeneratorArgs the generator
+// writes an input (`generator <args>`), the validator (if any) must accept it on stdin (exit code 0),
+// and the reference solution (the job's code) produces its output within the problem's limits.
+// The tests are stored only if all of them were produced; they replace the previously generated ones.
+func generateTests(job Job) JobResult {
+	startTime := time.Now()
+	limits := limitsFor(job)
+
+	result := func(verdict Verdict, outcome runOutcome, message string, tests []TestCaseResult) JobResult {
+		return JobResult{
+			JobID:      job.ID,
+			Status:     statusFor(verdict, true),
+			Error:      message,
+			ExecTime:   time.Since(startTime).Milliseconds(),
+			Timestamp:  time.Now(),
+			TestCases:  len(tests),
+			TotalCases: len(job.GeneratorArgs),
+			ProblemID:  job.ProblemID,
+			Language:   job.Language,
+			Verdict:    verdict,
+			ExitCode:   outcome.ExitCode,
+			Signal:     outcome.signal(),
+			Stderr:     outcome.Stderr,
+			Tests:      tests,
+		}
+	}
+	internalError := func(err error) JobResult {
+		return result(VerdictInternalError, runOutcome{}, err.Error(), nil)
+	}
+
+	if job.Generator == nil || job.Generator.Code == "" {
+		return internalError(fmt.Errorf("the job has no generator"))
+	}
+	if len(job.GeneratorArgs) == 0 || len(job.GeneratorArgs) > maxGeneratedTests {
+		return internalError(fmt.Errorf("a generation job needs between 1 and %d tests", maxGeneratedTests))
+	}
+
+	// Prepare and compile the three programs; the helpers get the checker's limits
+	setStage(job.ID, StageCompiling, 0, 0)
+	referenceCode, err := programCode(job, job.Language, job.Code)
+	if err != nil {
+		return internalError(err)
+	}
+	type program struct {
+		name string // of the executor, and in error messages
+		Program
+		limits Limits
+	}
+	programs := []program{
+		{"generator", *job.Generator, checkerLimits},
+		{"reference", Program{Language: job.Language, Code: referenceCode}, limits},
+	}
+	if job.Validator != nil && job.Validator.Code != "" {
+		programs = append(programs, program{"validator", *job.Validator, checkerLimits})
+	}
+	executors := make(map[string]Executor)
+	for _, p := range programs {
+		executor, err := prepareExecutor(job.ID+"-"+p.name, p.Language, p.Code, p.limits)
+		if err != nil {
+			return internalError(fmt.Errorf("%s: %v", p.name, err))
+		}
+		defer executor.Cleanup()
+
+		if compile := executor.Compile(); compile.Err != nil || compile.ExitCode != 0 {
+			verdict, message := compileFailure(compile)
+			return result(verdict, compile, fmt.Sprintf("%s: %s", p.name, message), nil)
+		}
+		executors[p.name] = executor
+	}
+	generator, reference, validator := executors["generator"], executors["reference"], executors["validator"]
+
+	var tests []TestCaseResult
+	var generated []generatedTest
+	for i, args := range job.GeneratorArgs {
+		setStage(job.ID, StageRunning, i+1, len(job.GeneratorArgs))
+		fail := func(verdict Verdict, run runOutcome, message string) JobResult {
+			tc := TestCaseResult{Index: i + 1, Verdict: verdict, TimeMs: run.Elapsed.Milliseconds(), CPUTimeMs: run.CPUTime.Milliseconds(),
+				MemoryKB: run.MemoryKB, measured: run.UsageMeasured, Message: truncate(message)}
+			publishEvent(JobEvent{Type: EventTest, JobID: job.ID, Test: i + 1, Total: len(job.GeneratorArgs), Case: &tc})
+			return result(verdict, run, fmt.Sprintf("Test #%d (%s): %s", i+1, args, message), append(tests, tc))
+		}
+
+		gen := generator.Run(checkerLimits, nil, "ARGS="+args)
+		if verdict := classifyRun(gen, checkerLimits); verdict != "" {
+			return fail(verdict, gen, "the generator failed")
+		}
+		input := gen.Stdout
+
+		if validator != nil {
+			check := validator.Run(checkerLimits, strings.NewReader(input))
+			if check.Err != nil {
+				return fail(VerdictInternalError, check, fmt.Sprintf("validator: %v", check.Err))
+			}
+			if check.ExitCode != 0 {
+				return fail(VerdictWrongAnswer, check, strings.TrimSpace("the validator rejected the input\n"+check.Stderr+"\n"+check.Stdout))
+			}
+		}
+
+		run := reference.Run(limits, strings.NewReader(input))
+		if verdict := classifyRun(run, limits); verdict != "" {
+			message := "the reference solution failed"
+			if verdict == VerdictTimeLimit || verdict == VerdictMemoryLimit {
+				message = "the reference solution exceeded the " + limitDescription(verdict, limits)
+			}
+			return fail(verdict, run, message)
+		}
+
+		tc := TestCaseResult{
+			Index:     i + 1,
+			Verdict:   VerdictAccepted,
+			TimeMs:    run.Elapsed.Milliseconds(),
+			CPUTimeMs: run.CPUTime.Milliseconds(),
+			MemoryKB:  run.MemoryKB,
+			measured:  run.UsageMeasured,
+			Input:     truncate(input),
+			Actual:    truncate(strings.TrimSpace(run.Stdout)),
+			Message:   truncate(args),
+		}
+		tests = append(tests, tc)
+		generated = append(generated, generatedTest{Args: args, Input: input, Output: run.Stdout})
+		publishEvent(JobEvent{Type: EventTest, JobID: job.ID, Test: i + 1, Total: len(job.GeneratorArgs), Case: &tc})
+	}
+
+	if err := storeGeneratedTests(job, generated, tests); err != nil {
+		return internalError(fmt.Errorf("storing the generated tests: %v", err))
+	}
+	res := result(VerdictAccepted, runOutcome{}, "", tests)
+	res.Output = fmt.Sprintf("Generated %d tests.", len(generated))
+	res.Score = 100
+	return res
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Types of the parameters and results of function problems; "T[]" is an array of T
+const (
+	TypeInt    = "int"  // 32 bits
+	TypeLong   = "long" // 64 bits
+	TypeDouble = "double"
+	TypeBool   = "bool"
+	TypeString = "string"
+)
+
+// FunctionSpec is the signature of a function problem, shared with the API. The user writes the
+// function (a method of class Solution in the languages with classes) and the worker wraps it in a
+// harness: each test input has one JSON value per line for each parameter, and the expected output
+// is the JSON value of the result.
+type FunctionSpec struct {
+	Name    string          `json:"name"`
+	Params  []FunctionParam `json:"params"`
+	Returns string          `json:"returns"`
+}
+
+type FunctionParam struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// harnessLanguage builds the harness of one language around the user's code
+type harnessLanguage struct {
+	types map[string]string // type names of the language, by Type* (arrays are built by array)
+	array func(element string) string
+	build func(code string, fn *FunctionSpec, h harnessLanguage) string
+}
+
+// typeName is the language's name of t
+func (h harnessLanguage) typeName(t string) string {
+	if isArray(t) {
+		return h.array(h.types[strings.TrimSuffix(t, "[]")])
+	}
+	return h.types[t]
+}
+
+// Harnesses by language id (languages.json)
+var harnesses = map[string]harnessLanguage{
+	"python":     {build: pythonHarness},
+	"javascript": {build: javascriptHarness},
+	"go": {
+		types: map[string]string{TypeInt: "int", TypeLong: "int64", TypeDouble: "float64", TypeBool: "bool", TypeString: "string"},
+		array: func(e string) string { return "[]" + e },
+		build: goHarness,
+	},
+	"cpp": {
+		types: map[string]string{TypeInt: "int", TypeLong: "long long", TypeDouble: "double", TypeBool: "bool", TypeString: "string"},
+		array: func(e string) string { return "vector<" + e + ">" },
+		build: cppHarness,
+	},
+	"c": {
+		types: map[string]string{TypeInt: "int", TypeLong: "long long", TypeDouble: "double", TypeBool: "bool", TypeString: "char*"},
+		array: func(e string) string { return e + "*" },
+		build: cHarness,
+	},
+	"java": {
+		types: map[string]string{TypeInt: "int", TypeLong: "long", TypeDouble: "double", TypeBool: "boolean", TypeString: "String"},
+		array: func(e string) string { return e + "[]" },
+		build: javaHarness,
+	},
+	"csharp": {
+		types: map[string]string{TypeInt: "int", TypeLong: "long", TypeDouble: "double", TypeBool: "bool", TypeString: "string"},
+		array: func(e string) string { return e + "[]" },
+		build: csharpHarness,
+	},
+	"rust": {
+		types: map[string]string{TypeInt: "i32", TypeLong: "i64", TypeDouble: "f64", TypeBool: "bool", TypeString: "String"},
+		array: func(e string) string { return "Vec<" + e + ">" },
+		build: rustHarness,
+	},
+}
+
+// wrapFunction returns the program that runs the user's function on the arguments read from stdin
+func wrapFunction(language, code string, fn *FunctionSpec) (string, error) {
+	h, ok := harnesses[language]
+	if !ok {
+		return "", fmt.Errorf("function problems are not available in %s", language)
+	}
+	return h.build(code, fn, h), nil
+}
+
+// programCode is the code to run for a program of a job: wrapped in the harness for function problems
+func programCode(job Job, language, code string) (string, error) {
+	if job.Function == nil {
+		return code, nil
+	}
+	return wrapFunction(language, code, job.Function)
+}
+
+// fillTemplate replaces the @NAME@ placeholders of a harness template
+func fillTemplate(template string, fn *FunctionSpec, values ...string) string {
+	values = append(values, "@NAME@", fn.Name, "@COUNT@", strconv.Itoa(len(fn.Params)))
+	return strings.NewReplacer(values...).Replace(template)
+}
+
+// harnessMethod is the name of the harness' reader / writer method for t, e.g. IntArray
+func harnessMethod(t string) string {
+	element := strings.TrimSuffix(t, "[]")
+	name := strings.ToUpper(element[:1]) + element[1:]
+	if isArray(t) {
+		name += "Array"
+	}
+	return name
+}
+
+func isArray(t string) bool {
+	return strings.HasSuffix(t, "[]")
+}
+
+func pythonHarness(code string, fn *FunctionSpec, _ harnessLanguage) string {
+	return code + fillTemplate(pythonHarnessMain, fn)
+}
+
+func javascriptHarness(code string, fn *FunctionSpec, _ harnessLanguage) string {
+	longs := make([]string, len(fn.Params))
+	for i, p := range fn.Params {
+		longs[i] = strconv.FormatBool(strings.TrimSuffix(p.Type, "[]") == TypeLong)
+	}
+	return code + fillTemplate(javascriptHarnessMain, fn, "@LONGS@", "["+strings.Join(longs, ", ")+"]")
+}
+
+// Package clause of Go code, the harness writes its own
+var goPackageClause = regexp.MustCompile(`(?m)^\s*package\s+\w+\s*$`)
+
+func goHarness(code string, fn *FunctionSpec, h harnessLanguage) string {
+	var parse strings.Builder
+	var args []string
+	for i, p := range fn.Params {
+		fmt.Fprintf(&parse, "\tvar arg%d %s\n", i, h.typeName(p.Type))
+		fmt.Fprintf(&parse, "\tif err := _json.Unmarshal([]byte(lines[%d]), &arg%d); err != nil {\n", i, i)
+		fmt.Fprintf(&parse, "\t\t_fmt.Fprintf(_os.Stderr, \"invalid argument %d: %%v\\n\", err)\n\t\t_os.Exit(1)\n\t}\n", i+1)
+		args = append(args, fmt.Sprintf("arg%d", i))
+	}
+	// A nil slice would be written as null
+	empty := ""
+	if isArray(fn.Returns) {
+		empty = fmt.Sprintf("\tif result == nil {\n\t\tresult = %s{}\n\t}\n", h.typeName(fn.Returns))
+	}
+	code = goPackageClause.ReplaceAllString(code, "")
+	return goHarnessPrelude + code + fillTemplate(goHarnessMain, fn,
+		"@PARSE@\n", parse.String(), "@ARGS@", strings.Join(args, ", "), "@EMPTY@\n", empty)
+}
+
+func cppHarness(code string, fn *FunctionSpec, h harnessLanguage) string {
+	var parse strings.Builder
+	var args []string
+	for i, p := range fn.Params {
+		fmt.Fprintf(&parse, "    %s arg%d;\n    harness_::parse(lines[%d], arg%d);\n", h.typeName(p.Type), i, i, i)
+		args = append(args, fmt.Sprintf("arg%d", i))
+	}
+	return cppHarnessPrelude + code + cppHarnessHelpers + fillTemplate(cppHarnessMain, fn,
+		"@PARSE@\n", parse.String(), "@ARGS@", strings.Join(args, ", "))
+}
+
+// cHarness passes arrays as a pointer and a length (nums, numsSize), and array results return
+// their length through a last int* parameter, like LeetCode does
+func cHarness(code string, fn *FunctionSpec, h harnessLanguage) string {
+	var parse strings.Builder
+	var args []string
+	for i, p := range fn.Params {
+		fmt.Fprintf(&parse, "    harness_begin(harness_args[%d]);\n", i)
+		if isArray(p.Type) {
+			fmt.Fprintf(&parse, "    int harness_arg%dSize;\n    %s harness_arg%d = harness_read%s(&harness_arg%dSize);\n",
+				i, h.typeName(p.Type), i, harnessMethod(p.Type), i)
+			args = append(args, fmt.Sprintf("harness_arg%d", i), fmt.Sprintf("harness_arg%dSize", i))
+		} else {
+			fmt.Fprintf(&parse, "    %s harness_arg%d = harness_read%s();\n", h.typeName(p.Type), i, harnessMethod(p.Type))
+			args = append(args, fmt.Sprintf("harness_arg%d", i))
+		}
+		parse.WriteString("    harness_end();\n")
+	}
+
+	var call string
+	if isArray(fn.Returns) {
+		args = append(args, "&harness_returnSize")
+		call = fmt.Sprintf("    int harness_returnSize = 0;\n    %s harness_result = %s(%s);\n    harness_write%s(harness_result, harness_returnSize);\n",
+			h.typeName(fn.Returns), fn.Name, strings.Join(args, ", "), harnessMethod(fn.Returns))
+	} else {
+		call = fmt.Sprintf("    %s harness_result = %s(%s);\n    harness_write%s(harness_result);\n",
+			h.typeName(fn.Returns), fn.Name, strings.Join(args, ", "), harnessMethod(fn.Returns))
+	}
+	return cHarnessPrelude + code + cHarnessHelpers + fillTemplate(cHarnessMain, fn, "@PARSE@\n", parse.String(), "@CALL@\n", call)
+}
+
+// Java runs class Main from Main.java, where Solution cannot be public
+var javaPublicSolution = regexp.MustCompile(`\bpublic\s+(final\s+)?class\s+Solution\b`)
+
+func javaHarness(code string, fn *FunctionSpec, h harnessLanguage) string {
+	var parse strings.Builder
+	var args []string
+	for i, p := range fn.Params {
+		fmt.Fprintf(&parse, "        HarnessReader reader%d = new HarnessReader(lines.get(%d));\n", i, i)
+		fmt.Fprintf(&parse, "        %s arg%d = reader%d.read%s();\n        reader%d.end();\n", h.typeName(p.Type), i, i, harnessMethod(p.Type), i)
+		args = append(args, fmt.Sprintf("arg%d", i))
+	}
+	code = javaPublicSolution.ReplaceAllString(code, "class Solution")
+	return javaHarnessPrelude + code + javaHarnessHelpers + fillTemplate(javaHarnessMain, fn,
+		"@PARSE@\n", parse.String(), "@ARGS@", strings.Join(args, ", "))
+}
+
+func csharpHarness(code string, fn *FunctionSpec, h harnessLanguage) string {
+	var parse strings.Builder
+	var args []string
+	for i, p := range fn.Params {
+		fmt.Fprintf(&parse, "        var reader%d = new HarnessReader(lines[%d]);\n", i, i)
+		fmt.Fprintf(&parse, "        %s arg%d = reader%d.Read%s();\n        reader%d.End();\n", h.typeName(p.Type), i, i, harnessMethod(p.Type), i)
+		args = append(args, fmt.Sprintf("arg%d", i))
+	}
+	return csharpHarnessPrelude + code + csharpHarnessHelpers + fillTemplate(csharpHarnessMain, fn,
+		"@PARSE@\n", parse.String(), "@ARGS@", strings.Join(args, ", "))
+}
+
+// rustHarness calls the function by its snake_case name, as the starter code declares it
+func rustHarness(code string, fn *FunctionSpec, h harnessLanguage) string {
+	var parse strings.Builder
+	var args []string
+	for i, p := range fn.Params {
+		fmt.Fprintf(&parse, "    let arg%d: %s = harness_parse(lines[%d]);\n", i, h.typeName(p.Type), i)
+		args = append(args, fmt.Sprintf("arg%d", i))
+	}
+	return rustHarnessPrelude + code + rustHarnessHelpers + fillTemplate(rustHarnessMain, fn,
+		"@PARSE@\n", parse.String(), "@ARGS@", strings.Join(args, ", "), "@RUST_NAME@", snakeCase(fn.Name))
+}
+
+// snakeCase writes a camelCase identifier in snake_case, the Rust style for functions:
+// twoSum -> two_sum, isValidBST -> is_valid_bst. Identifiers are ASCII.
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c >= 'A' && c <= 'Z' {
+			prevLower := i > 0 && (name[i-1] >= 'a' && name[i-1] <= 'z' || name[i-1] >= '0' && name[i-1] <= '9')
+			acronymEnd := i > 0 && name[i-1] >= 'A' && name[i-1] <= 'Z' && i+1 < len(name) && name[i+1] >= 'a' && name[i+1] <= 'z'
+			if prevLower || acronymEnd {
+				b.WriteByte('_')
+			}
+			c += 'a' - 'A'
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,1269 @@
+package main
+
+// Sources of the function harnesses (harness.go). Each language has a prelude before the user's
+// code, helpers after it and a main reading one JSON argument per non-blank line of stdin, calling
+// the function and writing its result as JSON. The placeholders @NAME@ (function), @COUNT@ (number
+// of parameters), @PARSE@ and @ARGS@ (argument declarations and list), @CALL@, @LONGS@ (which
+// parameters are long, for JavaScript) and @RUST_NAME@ (the function in snake_case) are filled in
+// by the builder of each language. Identifiers of the harness are prefixed so they do not collide
+// with the user's.
+
+const pythonHarnessMain = `
+
+def _harness_main():
+    import json, sys
+    lines = [line for line in sys.stdin.read().split("\n") if line.strip()]
+    if len(lines) != @COUNT@:
+        print("expected @COUNT@ argument lines, got %d" % len(lines), file=sys.stderr)
+        sys.exit(1)
+    result = Solution().@NAME@(*[json.loads(line) for line in lines])
+    print(json.dumps(result, separators=(",", ":"), ensure_ascii=False))
+
+
+_harness_main()
+`
+
+const javascriptHarnessMain = `
+
+(() => {
+  const lines = require("fs").readFileSync(0, "utf8").split("\n").filter((line) => line.trim() !== "");
+  if (lines.length !== @COUNT@) {
+    process.stderr.write("expected @COUNT@ argument lines, got " + lines.length + "\n");
+    process.exit(1);
+  }
+  // Numbers are exact integers only up to 2^53: a long argument past that would be rounded
+  const longs = @LONGS@;
+  const args = lines.map((line, i) => {
+    const value = JSON.parse(line);
+    if (longs[i] && [].concat(value).some((x) => !Number.isSafeInteger(x))) {
+      process.stderr.write("invalid argument " + (i + 1) + ": integer beyond 2^53, not exact in JavaScript\n");
+      process.exit(1);
+    }
+    return value;
+  });
+  // BigInt results are written as exact numbers
+  const write = (v) =>
+    typeof v === "bigint" ? v.toString() : Array.isArray(v) ? "[" + v.map(write).join(",") + "]" : JSON.stringify(v ?? null);
+  process.stdout.write(write(@NAME@(...args)) + "\n");
+})();
+`
+
+const goHarnessPrelude = `package main
+
+import (
+	_json "encoding/json"
+	_fmt "fmt"
+	_io "io"
+	_os "os"
+	_strings "strings"
+)
+
+`
+
+const goHarnessMain = `
+
+func main() {
+	data, err := _io.ReadAll(_os.Stdin)
+	if err != nil {
+		_fmt.Fprintln(_os.Stderr, err)
+		_os.Exit(1)
+	}
+	var lines []string
+	for _, line := range _strings.Split(string(data), "\n") {
+		if _strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) != @COUNT@ {
+		_fmt.Fprintf(_os.Stderr, "expected @COUNT@ argument lines, got %d\n", len(lines))
+		_os.Exit(1)
+	}
+@PARSE@
+	result := @NAME@(@ARGS@)
+@EMPTY@
+	encoder := _json.NewEncoder(_os.Stdout)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(result); err != nil {
+		_fmt.Fprintln(_os.Stderr, err)
+		_os.Exit(1)
+	}
+}
+`
+
+const cppHarnessPrelude = `#include <bits/stdc++.h>
+using namespace std;
+
+`
+
+const cppHarnessHelpers = `
+
+namespace harness_ {
+
+struct Reader {
+    const string& s;
+    size_t i = 0;
+
+    explicit Reader(const string& line) : s(line) {}
+
+    [[noreturn]] void fail(const char* what) {
+        fprintf(stderr, "invalid argument: expected %s at \"%s\"\n", what, s.c_str());
+        exit(1);
+    }
+    void skip() {
+        while (i < s.size() && isspace((unsigned char)s[i])) i++;
+    }
+    bool eat(char c) {
+        skip();
+        if (i < s.size() && s[i] == c) {
+            i++;
+            return true;
+        }
+        return false;
+    }
+    string number() {
+        skip();
+        size_t start = i;
+        while (i < s.size() && (isdigit((unsigned char)s[i]) || strchr("+-.eE", s[i]))) i++;
+        if (start == i) fail("a number");
+        return s.substr(start, i - start);
+    }
+    unsigned hex4() {
+        if (i + 4 > s.size()) fail("an escape");
+        unsigned code = 0;
+        for (int k = 0; k < 4; k++) {
+            char c = s[i++];
+            if (!isxdigit((unsigned char)c)) fail("an escape");
+            code = code * 16 + (isdigit((unsigned char)c) ? c - '0' : tolower(c) - 'a' + 10);
+        }
+        return code;
+    }
+
+    void read(long long& v) {
+        string n = number();
+        char* end;
+        errno = 0;
+        v = strtoll(n.c_str(), &end, 10);
+        if (*end || errno) fail("an integer");
+    }
+    void read(int& v) {
+        long long x;
+        read(x);
+        if (x < INT_MIN || x > INT_MAX) fail("a 32-bit integer");
+        v = (int)x;
+    }
+    void read(double& v) {
+        string n = number();
+        char* end;
+        v = strtod(n.c_str(), &end);
+        if (*end) fail("a number");
+    }
+    void read(bool& v) {
+        skip();
+        if (s.compare(i, 4, "true") == 0) {
+            v = true;
+            i += 4;
+        } else if (s.compare(i, 5, "false") == 0) {
+            v = false;
+            i += 5;
+        } else {
+            fail("true or false");
+        }
+    }
+    void read(string& v) {
+        if (!eat('"')) fail("a string");
+        v.clear();
+        for (;;) {
+            if (i >= s.size()) fail("the end of the string");
+            char c = s[i++];
+            if (c == '"') return;
+            if (c != '\\') {
+                v += c;
+                continue;
+            }
+            if (i >= s.size()) fail("an escape");
+            char e = s[i++];
+            switch (e) {
+            case 'n': v += '\n'; break;
+            case 't': v += '\t'; break;
+            case 'r': v += '\r'; break;
+            case 'b': v += '\b'; break;
+            case 'f': v += '\f'; break;
+            case 'u': {
+                unsigned code = hex4();
+                if (code >= 0xD800 && code < 0xDC00 && s.compare(i, 2, "\\u") == 0) {
+                    i += 2;
+                    unsigned low = hex4();
+                    if (low < 0xDC00 || low >= 0xE000) fail("a surrogate pair");
+                    code = 0x10000 + ((code - 0xD800) << 10) + (low - 0xDC00);
+                }
+                if (code < 0x80) {
+                    v += (char)code;
+                } else if (code < 0x800) {
+                    v += (char)(0xC0 | code >> 6);
+                    v += (char)(0x80 | (code & 0x3F));
+                } else if (code < 0x10000) {
+                    v += (char)(0xE0 | code >> 12);
+                    v += (char)(0x80 | (code >> 6 & 0x3F));
+                    v += (char)(0x80 | (code & 0x3F));
+                } else {
+                    v += (char)(0xF0 | code >> 18);
+                    v += (char)(0x80 | (code >> 12 & 0x3F));
+                    v += (char)(0x80 | (code >> 6 & 0x3F));
+                    v += (char)(0x80 | (code & 0x3F));
+                }
+                break;
+            }
+            default: v += e;
+            }
+        }
+    }
+    template <typename T>
+    void read(vector<T>& v) {
+        if (!eat('[')) fail("an array");
+        v.clear();
+        if (eat(']')) return;
+        do {
+            T x;
+            read(x);
+            v.push_back(x);
+        } while (eat(','));
+        if (!eat(']')) fail("] or ,");
+    }
+    void end() {
+        skip();
+        if (i != s.size()) fail("the end of the argument");
+    }
+};
+
+template <typename T>
+void parse(const string& line, T& v) {
+    Reader reader(line);
+    reader.read(v);
+    reader.end();
+}
+
+inline void write(string& out, int v) { out += to_string(v); }
+inline void write(string& out, long v) { out += to_string(v); }
+inline void write(string& out, long long v) { out += to_string(v); }
+inline void write(string& out, bool v) { out += v ? "true" : "false"; }
+inline void write(string& out, double v) {
+    char buffer[32];
+    snprintf(buffer, sizeof buffer, "%.17g", v);
+    out += buffer;
+}
+inline void write(string& out, const string& v) {
+    out += '"';
+    for (char c : v) {
+        switch (c) {
+        case '"': out += "\\\""; break;
+        case '\\': out += "\\\\"; break;
+        case '\n': out += "\\n"; break;
+        case '\r': out += "\\r"; break;
+        case '\t': out += "\\t"; break;
+        default:
+            if ((unsigned char)c < 0x20) {
+                char buffer[8];
+                snprintf(buffer, sizeof buffer, "\\u%04x", c);
+                out += buffer;
+            } else {
+                out += c;
+            }
+        }
+    }
+    out += '"';
+}
+template <typename T>
+void write(string& out, const vector<T>& v) {
+    out += '[';
+    for (size_t k = 0; k < v.size(); k++) {
+        if (k > 0) out += ',';
+        const T& x = v[k];
+        write(out, x);
+    }
+    out += ']';
+}
+
+}  // namespace harness_
+`
+
+const cppHarnessMain = `
+int main() {
+    vector<string> lines;
+    string line;
+    while (getline(cin, line)) {
+        if (line.find_first_not_of(" \t\r") != string::npos) lines.push_back(line);
+    }
+    if (lines.size() != @COUNT@) {
+        fprintf(stderr, "expected @COUNT@ argument lines, got %zu\n", lines.size());
+        return 1;
+    }
+@PARSE@
+    auto result = Solution().@NAME@(@ARGS@);
+    string out;
+    harness_::write(out, result);
+    out += '\n';
+    fwrite(out.data(), 1, out.size(), stdout);
+    return 0;
+}
+`
+
+const cHarnessPrelude = `#include <ctype.h>
+#include <errno.h>
+#include <limits.h>
+#include <math.h>
+#include <stdbool.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+`
+
+const cHarnessHelpers = `
+
+static const char* harness_s;
+static size_t harness_i;
+
+static void harness_fail(const char* what) {
+    fprintf(stderr, "invalid argument: expected %s at \"%s\"\n", what, harness_s);
+    exit(1);
+}
+
+static void harness_skip(void) {
+    while (harness_s[harness_i] && isspace((unsigned char)harness_s[harness_i])) harness_i++;
+}
+
+static bool harness_eat(char c) {
+    harness_skip();
+    if (harness_s[harness_i] == c) {
+        harness_i++;
+        return true;
+    }
+    return false;
+}
+
+static void harness_begin(const char* line) {
+    harness_s = line;
+    harness_i = 0;
+}
+
+static void harness_end(void) {
+    harness_skip();
+    if (harness_s[harness_i]) harness_fail("the end of the argument");
+}
+
+static long long harness_readLong(void) {
+    harness_skip();
+    const char* start = harness_s + harness_i;
+    char* end;
+    errno = 0;
+    long long v = strtoll(start, &end, 10);
+    if (end == start || errno) harness_fail("an integer");
+    harness_i += end - start;
+    return v;
+}
+
+static int harness_readInt(void) {
+    long long v = harness_readLong();
+    if (v < INT_MIN || v > INT_MAX) harness_fail("a 32-bit integer");
+    return (int)v;
+}
+
+static double harness_readDouble(void) {
+    harness_skip();
+    const char* start = harness_s + harness_i;
+    char* end;
+    double v = strtod(start, &end);
+    if (end == start) harness_fail("a number");
+    harness_i += end - start;
+    return v;
+}
+
+static bool harness_readBool(void) {
+    harness_skip();
+    if (strncmp(harness_s + harness_i, "true", 4) == 0) {
+        harness_i += 4;
+        return true;
+    }
+    if (strncmp(harness_s + harness_i, "false", 5) == 0) {
+        harness_i += 5;
+        return false;
+    }
+    harness_fail("true or false");
+    return false;
+}
+
+static unsigned harness_hex4(void) {
+    unsigned code = 0;
+    for (int k = 0; k < 4; k++) {
+        char c = harness_s[harness_i];
+        if (!isxdigit((unsigned char)c)) harness_fail("an escape");
+        harness_i++;
+        code = code * 16 + (isdigit((unsigned char)c) ? c - '0' : tolower(c) - 'a' + 10);
+    }
+    return code;
+}
+
+static char* harness_readString(void) {
+    if (!harness_eat('"')) harness_fail("a string");
+    /* Unescaping never makes a string longer */
+    char* v = malloc(strlen(harness_s + harness_i) + 1);
+    size_t n = 0;
+    for (;;) {
+        char c = harness_s[harness_i];
+        if (!c) harness_fail("the end of the string");
+        harness_i++;
+        if (c == '"') break;
+        if (c != '\\') {
+            v[n++] = c;
+            continue;
+        }
+        char e = harness_s[harness_i];
+        if (!e) harness_fail("an escape");
+        harness_i++;
+        switch (e) {
+        case 'n': v[n++] = '\n'; break;
+        case 't': v[n++] = '\t'; break;
+        case 'r': v[n++] = '\r'; break;
+        case 'b': v[n++] = '\b'; break;
+        case 'f': v[n++] = '\f'; break;
+        case 'u': {
+            unsigned code = harness_hex4();
+            if (code >= 0xD800 && code < 0xDC00 && strncmp(harness_s + harness_i, "\\u", 2) == 0) {
+                harness_i += 2;
+                unsigned low = harness_hex4();
+                if (low < 0xDC00 || low >= 0xE000) harness_fail("a surrogate pair");
+                code = 0x10000 + ((code - 0xD800) << 10) + (low - 0xDC00);
+            }
+            if (code < 0x80) {
+                v[n++] = (char)code;
+            } else if (code < 0x800) {
+                v[n++] = (char)(0xC0 | code >> 6);
+                v[n++] = (char)(0x80 | (code & 0x3F));
+            } else if (code < 0x10000) {
+                v[n++] = (char)(0xE0 | code >> 12);
+                v[n++] = (char)(0x80 | (code >> 6 & 0x3F));
+                v[n++] = (char)(0x80 | (code & 0x3F));
+            } else {
+                v[n++] = (char)(0xF0 | code >> 18);
+                v[n++] = (char)(0x80 | (code >> 12 & 0x3F));
+                v[n++] = (char)(0x80 | (code >> 6 & 0x3F));
+                v[n++] = (char)(0x80 | (code & 0x3F));
+            }
+            break;
+        }
+        default: v[n++] = e;
+        }
+    }
+    v[n] = 0;
+    return v;
+}
+
+#define HARNESS_READ_ARRAY(name, type)                                            \
+    static type* harness_read##name##Array(int* size) {                          \
+        int capacity = 16;                                                       \
+        type* v = malloc(capacity * sizeof(type));                               \
+        *size = 0;                                                               \
+        if (!harness_eat('[')) harness_fail("an array");                         \
+        if (harness_eat(']')) return v;                                          \
+        do {                                                                     \
+            if (*size == capacity) v = realloc(v, (capacity *= 2) * sizeof(type)); \
+            v[(*size)++] = harness_read##name();                                 \
+        } while (harness_eat(','));                                              \
+        if (!harness_eat(']')) harness_fail("] or ,");                           \
+        return v;                                                                \
+    }
+
+HARNESS_READ_ARRAY(Int, int)
+HARNESS_READ_ARRAY(Long, long long)
+HARNESS_READ_ARRAY(Double, double)
+HARNESS_READ_ARRAY(Bool, bool)
+HARNESS_READ_ARRAY(String, char*)
+
+static void harness_putInt(int v) { printf("%d", v); }
+static void harness_putLong(long long v) { printf("%lld", v); }
+static void harness_putDouble(double v) { printf("%.17g", v); }
+static void harness_putBool(bool v) { fputs(v ? "true" : "false", stdout); }
+
+static void harness_putString(const char* v) {
+    if (!v) {
+        fputs("null", stdout);
+        return;
+    }
+    putchar('"');
+    for (; *v; v++) {
+        switch (*v) {
+        case '"': fputs("\\\"", stdout); break;
+        case '\\': fputs("\\\\", stdout); break;
+        case '\n': fputs("\\n", stdout); break;
+        case '\r': fputs("\\r", stdout); break;
+        case '\t': fputs("\\t", stdout); break;
+        default:
+            if ((unsigned char)*v < 0x20) {
+                printf("\\u%04x", *v);
+            } else {
+                putchar(*v);
+            }
+        }
+    }
+    putchar('"');
+}
+
+#define HARNESS_WRITE(name, type)                                   \
+    static void harness_write##name(type v) {                       \
+        harness_put##name(v);                                       \
+        putchar('\n');                                              \
+    }                                                               \
+    static void harness_write##name##Array(type* v, int size) {     \
+        if (!v && size > 0) {                                       \
+            puts("null");                                           \
+            return;                                                 \
+        }                                                           \
+        putchar('[');                                               \
+        for (int k = 0; k < size; k++) {                            \
+            if (k > 0) putchar(',');                                \
+            harness_put##name(v[k]);                                \
+        }                                                           \
+        puts("]");                                                  \
+    }
+
+HARNESS_WRITE(Int, int)
+HARNESS_WRITE(Long, long long)
+HARNESS_WRITE(Double, double)
+HARNESS_WRITE(Bool, bool)
+HARNESS_WRITE(String, char*)
+
+/* harness_lines reads stdin and keeps up to max of its non-blank lines in lines, returning how many there are */
+static int harness_lines(char** lines, int max) {
+    size_t size = 0, capacity = 1 << 16, n;
+    char* input = malloc(capacity);
+    while ((n = fread(input + size, 1, capacity - size - 1, stdin)) > 0) {
+        size += n;
+        if (capacity - size == 1) input = realloc(input, capacity *= 2);
+    }
+    input[size] = 0;
+
+    int count = 0;
+    for (char* line = strtok(input, "\n"); line; line = strtok(NULL, "\n")) {
+        const char* c = line;
+        while (*c && isspace((unsigned char)*c)) c++;
+        if (!*c) continue;
+        if (count < max) lines[count] = line;
+        count++;
+    }
+    return count;
+}
+`
+
+const cHarnessMain = `
+int main(void) {
+    char* harness_args[@COUNT@ + 1];
+    int harness_count = harness_lines(harness_args, @COUNT@ + 1);
+    if (harness_count != @COUNT@) {
+        fprintf(stderr, "expected @COUNT@ argument lines, got %d\n", harness_count);
+        return 1;
+    }
+@PARSE@
+@CALL@
+    return 0;
+}
+`
+
+const javaHarnessPrelude = `import java.io.*;
+import java.nio.charset.StandardCharsets;
+import java.util.*;
+import java.util.function.*;
+
+`
+
+const javaHarnessHelpers = `
+
+class HarnessReader {
+    private final String s;
+    private int i;
+
+    HarnessReader(String line) {
+        s = line;
+    }
+
+    private RuntimeException fail(String what) {
+        System.err.println("invalid argument: expected " + what + " at \"" + s + "\"");
+        System.exit(1);
+        return new IllegalStateException();
+    }
+
+    private void skip() {
+        while (i < s.length() && Character.isWhitespace(s.charAt(i))) i++;
+    }
+
+    private boolean eat(char c) {
+        skip();
+        if (i < s.length() && s.charAt(i) == c) {
+            i++;
+            return true;
+        }
+        return false;
+    }
+
+    private String number() {
+        skip();
+        int start = i;
+        while (i < s.length() && "+-.eE0123456789".indexOf(s.charAt(i)) >= 0) i++;
+        if (start == i) throw fail("a number");
+        return s.substring(start, i);
+    }
+
+    long readLong() {
+        try {
+            return Long.parseLong(number());
+        } catch (NumberFormatException e) {
+            throw fail("an integer");
+        }
+    }
+
+    int readInt() {
+        long v = readLong();
+        if (v < Integer.MIN_VALUE || v > Integer.MAX_VALUE) throw fail("a 32-bit integer");
+        return (int) v;
+    }
+
+    double readDouble() {
+        try {
+            return Double.parseDouble(number());
+        } catch (NumberFormatException e) {
+            throw fail("a number");
+        }
+    }
+
+    boolean readBool() {
+        skip();
+        if (s.startsWith("true", i)) {
+            i += 4;
+            return true;
+        }
+        if (s.startsWith("false", i)) {
+            i += 5;
+            return false;
+        }
+        throw fail("true or false");
+    }
+
+    String readString() {
+        if (!eat('"')) throw fail("a string");
+        StringBuilder v = new StringBuilder();
+        while (true) {
+            if (i >= s.length()) throw fail("the end of the string");
+            char c = s.charAt(i++);
+            if (c == '"') return v.toString();
+            if (c != '\\') {
+                v.append(c);
+                continue;
+            }
+            if (i >= s.length()) throw fail("an escape");
+            char e = s.charAt(i++);
+            switch (e) {
+                case 'n': v.append('\n'); break;
+                case 't': v.append('\t'); break;
+                case 'r': v.append('\r'); break;
+                case 'b': v.append('\b'); break;
+                case 'f': v.append('\f'); break;
+                case 'u':
+                    if (i + 4 > s.length()) throw fail("an escape");
+                    try {
+                        v.append((char) Integer.parseInt(s.substring(i, i + 4), 16));
+                    } catch (NumberFormatException x) {
+                        throw fail("an escape");
+                    }
+                    i += 4;
+                    break;
+                default: v.append(e);
+            }
+        }
+    }
+
+    private <T> List<T> readList(Supplier<T> element) {
+        if (!eat('[')) throw fail("an array");
+        List<T> v = new ArrayList<>();
+        if (eat(']')) return v;
+        do {
+            v.add(element.get());
+        } while (eat(','));
+        if (!eat(']')) throw fail("] or ,");
+        return v;
+    }
+
+    int[] readIntArray() {
+        return readList(this::readInt).stream().mapToInt(Integer::intValue).toArray();
+    }
+
+    long[] readLongArray() {
+        return readList(this::readLong).stream().mapToLong(Long::longValue).toArray();
+    }
+
+    double[] readDoubleArray() {
+        return readList(this::readDouble).stream().mapToDouble(Double::doubleValue).toArray();
+    }
+
+    boolean[] readBoolArray() {
+        List<Boolean> list = readList(this::readBool);
+        boolean[] v = new boolean[list.size()];
+        for (int k = 0; k < v.length; k++) v[k] = list.get(k);
+        return v;
+    }
+
+    String[] readStringArray() {
+        return readList(this::readString).toArray(new String[0]);
+    }
+
+    void end() {
+        skip();
+        if (i != s.length()) throw fail("the end of the argument");
+    }
+}
+
+class HarnessWriter {
+    static void write(StringBuilder out, Object v) {
+        if (v == null) {
+            out.append("null");
+        } else if (v instanceof String) {
+            writeString(out, (String) v);
+        } else if (v instanceof Number || v instanceof Boolean) {
+            out.append(v);
+        } else if (v instanceof Character) {
+            writeString(out, v.toString());
+        } else if (v.getClass().isArray()) {
+            out.append('[');
+            for (int k = 0; k < java.lang.reflect.Array.getLength(v); k++) {
+                if (k > 0) out.append(',');
+                write(out, java.lang.reflect.Array.get(v, k));
+            }
+            out.append(']');
+        } else if (v instanceof Iterable) {
+            out.append('[');
+            boolean first = true;
+            for (Object x : (Iterable<?>) v) {
+                if (!first) out.append(',');
+                first = false;
+                write(out, x);
+            }
+            out.append(']');
+        } else {
+            writeString(out, v.toString());
+        }
+    }
+
+    private static void writeString(StringBuilder out, String v) {
+        out.append('"');
+        for (char c : v.toCharArray()) {
+            switch (c) {
+                case '"': out.append("\\\""); break;
+                case '\\': out.append("\\\\"); break;
+                case '\n': out.append("\\n"); break;
+                case '\r': out.append("\\r"); break;
+                case '\t': out.append("\\t"); break;
+                default:
+                    if (c < 0x20) {
+                        out.append(String.format("\\u%04x", (int) c));
+                    } else {
+                        out.append(c);
+                    }
+            }
+        }
+        out.append('"');
+    }
+}
+`
+
+const javaHarnessMain = `
+public class Main {
+    public static void main(String[] args) throws IOException {
+        BufferedReader in = new BufferedReader(new InputStreamReader(System.in, StandardCharsets.UTF_8));
+        List<String> lines = new ArrayList<>();
+        for (String line = in.readLine(); line != null; line = in.readLine()) {
+            if (!line.trim().isEmpty()) lines.add(line);
+        }
+        if (lines.size() != @COUNT@) {
+            System.err.println("expected @COUNT@ argument lines, got " + lines.size());
+            System.exit(1);
+        }
+@PARSE@
+        StringBuilder out = new StringBuilder();
+        HarnessWriter.write(out, new Solution().@NAME@(@ARGS@));
+        PrintStream stdout = new PrintStream(new FileOutputStream(FileDescriptor.out), true, "UTF-8");
+        stdout.println(out);
+    }
+}
+`
+
+const csharpHarnessPrelude = `using System;
+using System.Collections.Generic;
+using System.Globalization;
+using System.IO;
+using System.Linq;
+using System.Text;
+
+`
+
+const csharpHarnessHelpers = `
+
+class HarnessReader
+{
+    private readonly string s;
+    private int i;
+
+    public HarnessReader(string line)
+    {
+        s = line;
+    }
+
+    private Exception Fail(string what)
+    {
+        Console.Error.WriteLine("invalid argument: expected " + what + " at \"" + s + "\"");
+        Environment.Exit(1);
+        return new InvalidOperationException();
+    }
+
+    private void Skip()
+    {
+        while (i < s.Length && char.IsWhiteSpace(s[i])) i++;
+    }
+
+    private bool Eat(char c)
+    {
+        Skip();
+        if (i < s.Length && s[i] == c)
+        {
+            i++;
+            return true;
+        }
+        return false;
+    }
+
+    private string Number()
+    {
+        Skip();
+        int start = i;
+        while (i < s.Length && "+-.eE0123456789".IndexOf(s[i]) >= 0) i++;
+        if (start == i) throw Fail("a number");
+        return s.Substring(start, i - start);
+    }
+
+    public long ReadLong()
+    {
+        long v;
+        if (!long.TryParse(Number(), NumberStyles.AllowLeadingSign, CultureInfo.InvariantCulture, out v)) throw Fail("an integer");
+        return v;
+    }
+
+    public int ReadInt()
+    {
+        long v = ReadLong();
+        if (v < int.MinValue || v > int.MaxValue) throw Fail("a 32-bit integer");
+        return (int)v;
+    }
+
+    public double ReadDouble()
+    {
+        double v;
+        if (!double.TryParse(Number(), NumberStyles.Float, CultureInfo.InvariantCulture, out v)) throw Fail("a number");
+        return v;
+    }
+
+    public bool ReadBool()
+    {
+        Skip();
+        if (string.CompareOrdinal(s, i, "true", 0, 4) == 0)
+        {
+            i += 4;
+            return true;
+        }
+        if (string.CompareOrdinal(s, i, "false", 0, 5) == 0)
+        {
+            i += 5;
+            return false;
+        }
+        throw Fail("true or false");
+    }
+
+    public string ReadString()
+    {
+        if (!Eat('"')) throw Fail("a string");
+        var v = new StringBuilder();
+        while (true)
+        {
+            if (i >= s.Length) throw Fail("the end of the string");
+            char c = s[i++];
+            if (c == '"') return v.ToString();
+            if (c != '\\')
+            {
+                v.Append(c);
+                continue;
+            }
+            if (i >= s.Length) throw Fail("an escape");
+            char e = s[i++];
+            switch (e)
+            {
+                case 'n': v.Append('\n'); break;
+                case 't': v.Append('\t'); break;
+                case 'r': v.Append('\r'); break;
+                case 'b': v.Append('\b'); break;
+                case 'f': v.Append('\f'); break;
+                case 'u':
+                    int code;
+                    if (i + 4 > s.Length || !int.TryParse(s.Substring(i, 4), NumberStyles.AllowHexSpecifier, CultureInfo.InvariantCulture, out code)) throw Fail("an escape");
+                    v.Append((char)code);
+                    i += 4;
+                    break;
+                default: v.Append(e); break;
+            }
+        }
+    }
+
+    private T[] ReadArray<T>(Func<T> element)
+    {
+        if (!Eat('[')) throw Fail("an array");
+        var v = new List<T>();
+        if (Eat(']')) return v.ToArray();
+        do
+        {
+            v.Add(element());
+        } while (Eat(','));
+        if (!Eat(']')) throw Fail("] or ,");
+        return v.ToArray();
+    }
+
+    public int[] ReadIntArray() { return ReadArray(ReadInt); }
+    public long[] ReadLongArray() { return ReadArray(ReadLong); }
+    public double[] ReadDoubleArray() { return ReadArray(ReadDouble); }
+    public bool[] ReadBoolArray() { return ReadArray(ReadBool); }
+    public string[] ReadStringArray() { return ReadArray(ReadString); }
+
+    public void End()
+    {
+        Skip();
+        if (i != s.Length) throw Fail("the end of the argument");
+    }
+}
+
+static class HarnessWriter
+{
+    public static void Write(StringBuilder sb, object v)
+    {
+        if (v == null)
+        {
+            sb.Append("null");
+        }
+        else if (v is string || v is char)
+        {
+            WriteString(sb, v.ToString());
+        }
+        else if (v is bool)
+        {
+            sb.Append((bool)v ? "true" : "false");
+        }
+        else if (v is double || v is float)
+        {
+            sb.Append(Convert.ToDouble(v).ToString("R", CultureInfo.InvariantCulture));
+        }
+        else if (v is System.Collections.IEnumerable)
+        {
+            sb.Append('[');
+            bool first = true;
+            foreach (object x in (System.Collections.IEnumerable)v)
+            {
+                if (!first) sb.Append(',');
+                first = false;
+                Write(sb, x);
+            }
+            sb.Append(']');
+        }
+        else
+        {
+            sb.Append(Convert.ToString(v, CultureInfo.InvariantCulture));
+        }
+    }
+
+    private static void WriteString(StringBuilder sb, string v)
+    {
+        sb.Append('"');
+        foreach (char c in v)
+        {
+            switch (c)
+            {
+                case '"': sb.Append("\\\""); break;
+                case '\\': sb.Append("\\\\"); break;
+                case '\n': sb.Append("\\n"); break;
+                case '\r': sb.Append("\\r"); break;
+                case '\t': sb.Append("\\t"); break;
+                default:
+                    if (c < 0x20) sb.Append("\\u" + ((int)c).ToString("x4"));
+                    else sb.Append(c);
+                    break;
+            }
+        }
+        sb.Append('"');
+    }
+}
+`
+
+const csharpHarnessMain = `
+class HarnessMain
+{
+    static int Main()
+    {
+        var input = new StreamReader(Console.OpenStandardInput(), new UTF8Encoding(false));
+        var lines = new List<string>();
+        string line;
+        while ((line = input.ReadLine()) != null)
+        {
+            if (line.Trim().Length > 0) lines.Add(line);
+        }
+        if (lines.Count != @COUNT@)
+        {
+            Console.Error.WriteLine("expected @COUNT@ argument lines, got " + lines.Count);
+            return 1;
+        }
+@PARSE@
+        var sb = new StringBuilder();
+        HarnessWriter.Write(sb, new Solution().@NAME@(@ARGS@));
+        var stdout = new StreamWriter(Console.OpenStandardOutput(), new UTF8Encoding(false));
+        stdout.WriteLine(sb.ToString());
+        stdout.Flush();
+        return 0;
+    }
+}
+`
+
+const rustHarnessPrelude = `#![allow(dead_code, non_snake_case, unused_imports)]
+
+struct Solution;
+
+`
+
+const rustHarnessHelpers = `
+
+struct HarnessReader<'a> {
+    line: &'a str,
+    s: &'a [u8],
+    i: usize,
+}
+
+impl<'a> HarnessReader<'a> {
+    fn fail(&self, what: &str) -> ! {
+        eprintln!("invalid argument: expected {} at \"{}\"", what, self.line);
+        std::process::exit(1);
+    }
+
+    fn skip(&mut self) {
+        while self.i < self.s.len() && self.s[self.i].is_ascii_whitespace() {
+            self.i += 1;
+        }
+    }
+
+    fn eat(&mut self, c: u8) -> bool {
+        self.skip();
+        if self.i < self.s.len() && self.s[self.i] == c {
+            self.i += 1;
+            true
+        } else {
+            false
+        }
+    }
+
+    fn number(&mut self) -> &'a str {
+        self.skip();
+        let start = self.i;
+        while self.i < self.s.len() && b"+-.eE0123456789".contains(&self.s[self.i]) {
+            self.i += 1;
+        }
+        if start == self.i {
+            self.fail("a number");
+        }
+        &self.line[start..self.i]
+    }
+
+    fn hex4(&mut self) -> u32 {
+        let line = self.line;
+        let digits = line.get(self.i..self.i + 4).unwrap_or_else(|| self.fail("an escape"));
+        let code = u32::from_str_radix(digits, 16).unwrap_or_else(|_| self.fail("an escape"));
+        self.i += 4;
+        code
+    }
+}
+
+trait HarnessValue: Sized {
+    fn read(reader: &mut HarnessReader) -> Self;
+    fn write(&self, out: &mut String);
+}
+
+impl HarnessValue for i64 {
+    fn read(reader: &mut HarnessReader) -> Self {
+        let n = reader.number();
+        n.parse().unwrap_or_else(|_| reader.fail("an integer"))
+    }
+    fn write(&self, out: &mut String) {
+        out.push_str(&self.to_string());
+    }
+}
+
+impl HarnessValue for i32 {
+    fn read(reader: &mut HarnessReader) -> Self {
+        let v = i64::read(reader);
+        i32::try_from(v).unwrap_or_else(|_| reader.fail("a 32-bit integer"))
+    }
+    fn write(&self, out: &mut String) {
+        out.push_str(&self.to_string());
+    }
+}
+
+impl HarnessValue for f64 {
+    fn read(reader: &mut HarnessReader) -> Self {
+        let n = reader.number();
+        n.parse().unwrap_or_else(|_| reader.fail("a number"))
+    }
+    fn write(&self, out: &mut String) {
+        out.push_str(&self.to_string());
+    }
+}
+
+impl HarnessValue for bool {
+    fn read(reader: &mut HarnessReader) -> Self {
+        reader.skip();
+        if reader.s[reader.i..].starts_with(b"true") {
+            reader.i += 4;
+            true
+        } else if reader.s[reader.i..].starts_with(b"false") {
+            reader.i += 5;
+            false
+        } else {
+            reader.fail("true or false")
+        }
+    }
+    fn write(&self, out: &mut String) {
+        out.push_str(if *self { "true" } else { "false" });
+    }
+}
+
+impl HarnessValue for String {
+    fn read(reader: &mut HarnessReader) -> Self {
+        if !reader.eat(b'"') {
+            reader.fail("a string");
+        }
+        let mut v: Vec<u8> = Vec::new();
+        loop {
+            if reader.i >= reader.s.len() {
+                reader.fail("the end of the string");
+            }
+            let c = reader.s[reader.i];
+            reader.i += 1;
+            match c {
+                b'"' => break,
+                b'\\' => {
+                    if reader.i >= reader.s.len() {
+                        reader.fail("an escape");
+                    }
+                    let e = reader.s[reader.i];
+                    reader.i += 1;
+                    match e {
+                        b'n' => v.push(b'\n'),
+                        b't' => v.push(b'\t'),
+                        b'r' => v.push(b'\r'),
+                        b'b' => v.push(8),
+                        b'f' => v.push(12),
+                        b'u' => {
+                            let mut code = reader.hex4();
+                            if (0xD800..0xDC00).contains(&code) && reader.s[reader.i..].starts_with(b"\\u") {
+                                reader.i += 2;
+                                let low = reader.hex4();
+                                if !(0xDC00..0xE000).contains(&low) {
+                                    reader.fail("a surrogate pair");
+                                }
+                                code = 0x10000 + ((code - 0xD800) << 10) + (low - 0xDC00);
+                            }
+                            let c = char::from_u32(code).unwrap_or('\u{FFFD}');
+                            let mut buffer = [0u8; 4];
+                            v.extend_from_slice(c.encode_utf8(&mut buffer).as_bytes());
+                        }
+                        _ => v.push(e),
+                    }
+                }
+                _ => v.push(c),
+            }
+        }
+        String::from_utf8(v).unwrap_or_else(|_| reader.fail("a UTF-8 string"))
+    }
+    fn write(&self, out: &mut String) {
+        out.push('"');
+        for c in self.chars() {
+            match c {
+                '"' => out.push_str("\\\""),
+                '\\' => out.push_str("\\\\"),
+                '\n' => out.push_str("\\n"),
+                '\r' => out.push_str("\\r"),
+                '\t' => out.push_str("\\t"),
+                c if (c as u32) < 0x20 => out.push_str(&format!("\\u{:04x}", c as u32)),
+                c => out.push(c),
+            }
+        }
+        out.push('"');
+    }
+}
+
+impl<T: HarnessValue> HarnessValue for Vec<T> {
+    fn read(reader: &mut HarnessReader) -> Self {
+        if !reader.eat(b'[') {
+            reader.fail("an array");
+        }
+        let mut v = Vec::new();
+        if reader.eat(b']') {
+            return v;
+        }
+        loop {
+            v.push(T::read(reader));
+            if !reader.eat(b',') {
+                break;
+            }
+        }
+        if !reader.eat(b']') {
+            reader.fail("] or ,");
+        }
+        v
+    }
+    fn write(&self, out: &mut String) {
+        out.push('[');
+        for (k, x) in self.iter().enumerate() {
+            if k > 0 {
+                out.push(',');
+            }
+            x.write(out);
+        }
+        out.push(']');
+    }
+}
+
+fn harness_parse<T: HarnessValue>(line: &str) -> T {
+    let mut reader = HarnessReader { line, s: line.as_bytes(), i: 0 };
+    let value = T::read(&mut reader);
+    reader.skip();
+    if reader.i != reader.s.len() {
+        reader.fail("the end of the argument");
+    }
+    value
+}
+`
+
+const rustHarnessMain = `
+fn main() {
+    let mut input = String::new();
+    std::io::Read::read_to_string(&mut std::io::stdin(), &mut input).unwrap();
+    let lines: Vec<&str> = input.lines().filter(|line| !line.trim().is_empty()).collect();
+    if lines.len() != @COUNT@ {
+        eprintln!("expected @COUNT@ argument lines, got {}", lines.len());
+        std::process::exit(1);
+    }
+@PARSE@
+    let result = Solution::@RUST_NAME@(@ARGS@);
+    let mut out = String::new();
+    HarnessValue::write(&result, &mut out);
+    println!("{}", out);
+}
+`
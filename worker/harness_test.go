@@ -0,0 +1,361 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"leetcode-clone/registry"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// Fixture of the harness tests: twoSum(nums int[], target int) int[]
+var twoSumSpec = &FunctionSpec{
+	Name:    "twoSum",
+	Params:  []FunctionParam{{Name: "nums", Type: "int[]"}, {Name: "target", Type: "int"}},
+	Returns: "int[]",
+}
+
+// twoSum in every language with a harness, declared the way its starter code does
+var twoSumSolutions = map[string]string{
+	"python": `class Solution:
+    def twoSum(self, nums, target):
+        for i in range(len(nums)):
+            for j in range(i + 1, len(nums)):
+                if nums[i] + nums[j] == target:
+                    return [i, j]
+        return []
+`,
+	"javascript": `function twoSum(nums, target) {
+  for (let i = 0; i < nums.length; i++) {
+    for (let j = i + 1; j < nums.length; j++) {
+      if (nums[i] + nums[j] === target) return [i, j];
+    }
+  }
+  return [];
+}
+`,
+	"go": `package main
+
+func twoSum(nums []int, target int) []int {
+	for i := range nums {
+		for j := i + 1; j < len(nums); j++ {
+			if nums[i]+nums[j] == target {
+				return []int{i, j}
+			}
+		}
+	}
+	return nil
+}
+`,
+	"cpp": `class Solution {
+public:
+    vector<int> twoSum(vector<int>& nums, int target) {
+        for (int i = 0; i < (int)nums.size(); i++)
+            for (int j = i + 1; j < (int)nums.size(); j++)
+                if (nums[i] + nums[j] == target) return {i, j};
+        return {};
+    }
+};
+`,
+	"c": `int* twoSum(int* nums, int numsSize, int target, int* returnSize) {
+    int* result = malloc(2 * sizeof(int));
+    *returnSize = 0;
+    for (int i = 0; i < numsSize; i++)
+        for (int j = i + 1; j < numsSize; j++)
+            if (nums[i] + nums[j] == target) {
+                result[0] = i;
+                result[1] = j;
+                *returnSize = 2;
+                return result;
+            }
+    return result;
+}
+`,
+	"java": `public class Solution {
+    public int[] twoSum(int[] nums, int target) {
+        for (int i = 0; i < nums.length; i++)
+            for (int j = i + 1; j < nums.length; j++)
+                if (nums[i] + nums[j] == target) return new int[] {i, j};
+        return new int[0];
+    }
+}
+`,
+	"csharp": `public class Solution {
+    public int[] twoSum(int[] nums, int target) {
+        for (int i = 0; i < nums.Length; i++)
+            for (int j = i + 1; j < nums.Length; j++)
+                if (nums[i] + nums[j] == target) return new int[] {i, j};
+        return new int[0];
+    }
+}
+`,
+	"rust": `impl Solution {
+    pub fn two_sum(nums: Vec<i32>, target: i32) -> Vec<i32> {
+        for i in 0..nums.len() {
+            for j in i + 1..nums.len() {
+                if nums[i] + nums[j] == target {
+                    return vec![i as i32, j as i32];
+                }
+            }
+        }
+        vec![]
+    }
+}
+`,
+}
+
+// identitySolution returns the code of identity(x t) t in language
+func identitySolution(language, t string) string {
+	switch language {
+	case "python":
+		return "class Solution:\n    def identity(self, x):\n        return x\n"
+	case "javascript":
+		return "function identity(x) {\n  return x;\n}\n"
+	}
+	name := harnesses[language].typeName(t)
+	switch language {
+	case "go":
+		return fmt.Sprintf("func identity(x %s) %s {\n\treturn x\n}\n", name, name)
+	case "cpp":
+		return fmt.Sprintf("class Solution {\npublic:\n    %s identity(%s x) {\n        return x;\n    }\n};\n", name, name)
+	case "c":
+		if isArray(t) {
+			return fmt.Sprintf("%s identity(%s x, int xSize, int* returnSize) {\n    *returnSize = xSize;\n    return x;\n}\n", name, name)
+		}
+		return fmt.Sprintf("%s identity(%s x) {\n    return x;\n}\n", name, name)
+	case "java":
+		return fmt.Sprintf("class Solution {\n    public %s identity(%s x) {\n        return x;\n    }\n}\n", name, name)
+	case "csharp":
+		return fmt.Sprintf("public class Solution {\n    public %s identity(%s x) {\n        return x;\n    }\n}\n", name, name)
+	case "rust":
+		return fmt.Sprintf("impl Solution {\n    pub fn identity(x: %s) -> %s {\n        x\n    }\n}\n", name, name)
+	}
+	return ""
+}
+
+// TestHarnessGolden compares the harness of every language for the twoSum fixture with
+// testdata/harness/<language>.golden (rewritten by go test -update)
+func TestHarnessGolden(t *testing.T) {
+	for language := range harnesses {
+		t.Run(language, func(t *testing.T) {
+			code, err := wrapFunction(language, twoSumSolutions[language], twoSumSpec)
+			if err != nil {
+				t.Fatalf("wrapFunction: %v", err)
+			}
+			golden := filepath.Join("testdata", "harness", language+".golden")
+			if *updateGolden {
+				if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(golden, []byte(code), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to write it)", err)
+			}
+			if code != string(want) {
+				t.Errorf("the harness differs from %s (run go test -update if the change is intended)", golden)
+			}
+		})
+	}
+}
+
+func TestHarnessRustSnakeCase(t *testing.T) {
+	code, err := wrapFunction("rust", "", &FunctionSpec{Name: "isValidBST", Returns: "bool"})
+	if err != nil {
+		t.Fatalf("wrapFunction: %v", err)
+	}
+	if !strings.Contains(code, "Solution::is_valid_bst()") {
+		t.Error("the Rust harness does not call is_valid_bst")
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	for name, want := range map[string]string{
+		"twoSum":      "two_sum",
+		"maxSubArray": "max_sub_array",
+		"isValidBST":  "is_valid_bst",
+		"getHTTPCode": "get_http_code",
+		"sum2Numbers": "sum2_numbers",
+		"already_ok":  "already_ok",
+		"x":           "x",
+		"Solve":       "solve",
+	} {
+		if got := snakeCase(name); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// useRegistry loads languages.json and makes prepareExecutor use the local backend
+func useRegistry(t *testing.T) {
+	t.Helper()
+	t.Setenv("EXECUTOR_BACKEND", backendLocal)
+	t.Setenv("LOCAL_CGROUP_ROOT", "/proc/no-cgroups-in-tests")
+	t.Setenv("LANGUAGES_FILE", filepath.Join("..", registry.DefaultFile))
+	saved := languages
+	t.Cleanup(func() { languages = saved })
+	if err := loadLanguages(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// prepareFunction compiles code wrapped in the harness of language with the local executor,
+// skipping the test if the toolchain of the language is not installed
+func prepareFunction(t *testing.T, language, code string, fn *FunctionSpec) Executor {
+	t.Helper()
+	lang := languages[language]
+	command := lang.Compile
+	if command == "" {
+		command = lang.Run
+	}
+	if _, err := exec.LookPath(strings.Fields(command)[0]); err != nil {
+		t.Skipf("%s is not installed", strings.Fields(command)[0])
+	}
+
+	program, err := wrapFunction(language, code, fn)
+	if err != nil {
+		t.Fatalf("wrapFunction: %v", err)
+	}
+	executor, err := prepareExecutor("harness-"+language, language, program, harnessLimits)
+	if err != nil {
+		t.Fatalf("prepareExecutor: %v", err)
+	}
+	t.Cleanup(executor.Cleanup)
+	if compile := executor.Compile(); compile.Err != nil || compile.ExitCode != 0 {
+		t.Fatalf("compile: %v (exit code %d): %s", compile.Err, compile.ExitCode, compile.Stderr)
+	}
+	return executor
+}
+
+var harnessLimits = Limits{CPUTime: 5 * time.Second, WallTime: 10 * time.Second, MemoryMB: 512}
+
+// harnessCase is an input of a harness run: its result must equal want (as JSON), or the program
+// must fail with an error containing fails
+type harnessCase struct {
+	input string
+	want  string
+	fails string
+}
+
+func runHarnessCases(t *testing.T, executor Executor, cases []harnessCase) {
+	t.Helper()
+	checker := newFunctionChecker(CheckerSpec{})
+	for _, c := range cases {
+		run := executor.Run(harnessLimits, strings.NewReader(c.input))
+		if c.fails != "" {
+			if run.Err != nil || run.ExitCode == 0 || !strings.Contains(run.Stderr, c.fails) {
+				t.Errorf("input %q: got exit code %d (%v) and stderr %q, want a failure with %q", c.input, run.ExitCode, run.Err, run.Stderr, c.fails)
+			}
+			continue
+		}
+		if run.Err != nil || run.ExitCode != 0 {
+			t.Errorf("input %q: exit code %d (%v): %s", c.input, run.ExitCode, run.Err, run.Stderr)
+			continue
+		}
+		if verdict, message := checker.Check(c.input, c.want, run.Stdout); verdict != VerdictAccepted {
+			t.Errorf("input %q: got %q, want %s (%s)", c.input, strings.TrimSpace(run.Stdout), c.want, message)
+		}
+	}
+}
+
+// TestHarnessRun runs the twoSum fixture in every language whose toolchain is installed
+func TestHarnessRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles a program per language")
+	}
+	useRegistry(t)
+	for language := range harnesses {
+		t.Run(language, func(t *testing.T) {
+			executor := prepareFunction(t, language, twoSumSolutions[language], twoSumSpec)
+			runHarnessCases(t, executor, []harnessCase{
+				{input: "[2,7,11,15]\n9\n", want: "[0,1]"},
+				{input: "\n[3, 2, 4]\r\n\n  6\r\n", want: "[1,2]"},
+				{input: "[]\n1\n", want: "[]"},
+				{input: "[2147483647,-2147483648]\n-1\n", want: "[0,1]"},
+				// The argument count is checked before parsing
+				{input: "[2,7,11,15]\n", fails: "expected 2 argument lines, got 1"},
+				{input: "[2,7]\n9\n[1]\n", fails: "expected 2 argument lines, got 3"},
+				{input: "", fails: "expected 2 argument lines, got 0"},
+			})
+		})
+	}
+}
+
+// TestHarnessTypes runs identity(x T) T for every type: the harness must read the argument and
+// write it back unchanged
+func TestHarnessTypes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles a program per language and type")
+	}
+	useRegistry(t)
+
+	const text = `"a \"quoted\" \\ line\nwith tab\t, é, 😀 and \u0001"`
+	tests := []struct {
+		typ   string
+		cases []harnessCase
+	}{
+		{TypeInt, []harnessCase{{input: "0", want: "0"}, {input: "-2147483648", want: "-2147483648"}, {input: "2147483647", want: "2147483647"}}},
+		{TypeLong, []harnessCase{{input: "-42", want: "-42"}, {input: "9007199254740991", want: "9007199254740991"}}},
+		{TypeDouble, []harnessCase{{input: "0.1", want: "0.1"}, {input: "-2.5e-300", want: "-2.5e-300"}, {input: "3", want: "3"}}},
+		{TypeBool, []harnessCase{{input: "true", want: "true"}, {input: "false", want: "false"}}},
+		{TypeString, []harnessCase{{input: `""`, want: `""`}, {input: text, want: text}}},
+		{TypeInt + "[]", []harnessCase{{input: "[]", want: "[]"}, {input: "[1, -2, 3]", want: "[1,-2,3]"}}},
+		{TypeLong + "[]", []harnessCase{{input: "[-9007199254740991, 0]", want: "[-9007199254740991,0]"}}},
+		{TypeDouble + "[]", []harnessCase{{input: "[0.5,-1e-9,100]", want: "[0.5,-1e-9,100]"}}},
+		{TypeBool + "[]", []harnessCase{{input: "[true,false]", want: "[true,false]"}}},
+		{TypeString + "[]", []harnessCase{{input: `["a", "", "b c"]`, want: `["a","","b c"]`}, {input: "[" + text + "]", want: "[" + text + "]"}}},
+	}
+	// Past 2^53 JavaScript numbers are not exact integers: its harness refuses such a long
+	beyondDouble := []harnessCase{
+		{input: "9223372036854775807", want: "9223372036854775807"},
+		{input: "-9223372036854775808", want: "-9223372036854775808"},
+		{input: "9007199254740993", want: "9007199254740993"},
+	}
+
+	for language := range harnesses {
+		t.Run(language, func(t *testing.T) {
+			for _, tt := range tests {
+				t.Run(strings.ReplaceAll(tt.typ, "[]", "Array"), func(t *testing.T) {
+					fn := &FunctionSpec{Name: "identity", Params: []FunctionParam{{Name: "x", Type: tt.typ}}, Returns: tt.typ}
+					executor := prepareFunction(t, language, identitySolution(language, tt.typ), fn)
+					cases := tt.cases
+					switch {
+					case tt.typ == TypeLong && language == "javascript":
+						for _, c := range beyondDouble {
+							cases = append(cases, harnessCase{input: c.input, fails: "integer beyond 2^53"})
+						}
+					case tt.typ == TypeLong:
+						cases = append(cases, beyondDouble...)
+					case tt.typ == TypeLong+"[]" && language == "javascript":
+						cases = append(cases, harnessCase{input: "[1, 9007199254740993]", fails: "integer beyond 2^53"})
+					}
+					runHarnessCases(t, executor, cases)
+				})
+			}
+		})
+	}
+}
+
+// JavaScript functions can compute a long result exactly as a BigInt
+func TestHarnessJavaScriptBigInt(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs node")
+	}
+	useRegistry(t)
+	fn := &FunctionSpec{Name: "square", Params: []FunctionParam{{Name: "x", Type: TypeLong}}, Returns: TypeLong + "[]"}
+	executor := prepareFunction(t, "javascript", "function square(x) {\n  return [BigInt(x) * BigInt(x), x];\n}\n", fn)
+	runHarnessCases(t, executor, []harnessCase{
+		{input: "3037000499", want: "[9223372030926249001,3037000499]"},
+	})
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Where the interactor finds the test input and writes its log, passed to it as arguments in
+// testlib order (`interactor input output answer`)
+const interactorLogFile = "judge/interactor.txt"
+
+// Shortest wait for the next message of an interaction before the silent side is judged idle,
+// and the extra time of the first turn, while both programs start
+const (
+	minTurnTimeout   = time.Second
+	turnStartupGrace = time.Second
+)
+
+// interactiveJudge runs the admin-supplied interactor of a problem (CheckerInteractive) in its own
+// executor. For each test it is started next to the program, each one's stdout connected to the
+// other's stdin, and answers with its exit code like a special judge.
+type interactiveJudge struct {
+	executor Executor
+}
+
+func newInteractiveJudge(name string, spec CheckerSpec) (*interactiveJudge, error) {
+	if spec.Code == "" {
+		return nil, fmt.Errorf("interactive problem has no interactor program")
+	}
+	executor, err := prepareExecutor(name, spec.Language, spec.Code, checkerLimits)
+	if err != nil {
+		return nil, fmt.Errorf("interactor: %v", err)
+	}
+	if compile := executor.Compile(); compile.Err != nil || compile.ExitCode != 0 {
+		executor.Cleanup()
+		_, message := compileFailure(compile)
+		return nil, fmt.Errorf("interactor: %s", message)
+	}
+	return &interactiveJudge{executor: executor}, nil
+}
+
+// Interact runs program against the interactor on one test. It returns the verdict, the
+// interactor's comment and the outcome of the program (whose Stdout is what it sent).
+// A side that stays silent for a whole turn (the program's CPU time limit) while the other one waits
+// ends the interaction: the program gets TLE, the interactor IE. Otherwise a program that failed
+// on its own (TLE, MLE, RE) gets that verdict, and the interactor's exit code decides the rest.
+func (j *interactiveJudge) Interact(program Executor, limits Limits, input, answer string) (Verdict, string, runOutcome) {
+	if err := j.executor.WriteFiles(map[string]string{judgeInputFile: input, judgeAnswerFile: answer}); err != nil {
+		return VerdictInternalError, fmt.Sprintf("interactor: %v", err), runOutcome{}
+	}
+
+	// Real pipes, so each process reads the other one's output directly and sees it end
+	toProgram, fromInteractor, err := os.Pipe()
+	if err != nil {
+		return VerdictInternalError, fmt.Sprintf("interactor: %v", err), runOutcome{}
+	}
+	toInteractor, fromProgram, err := os.Pipe()
+	if err != nil {
+		toProgram.Close()
+		fromInteractor.Close()
+		return VerdictInternalError, fmt.Sprintf("interactor: %v", err), runOutcome{}
+	}
+
+	turns := newTurnWatch(limits.CPUTime)
+	sent := &cappedBuffer{} // what the program sent, for the test preview
+	var run runOutcome
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		run = program.Interact(limits, toProgram, io.MultiWriter(sent, turns.relay(fromProgram, true)))
+		// The interactor reads the end of the program's output; nothing can reach the program anymore
+		fromProgram.Close()
+		toProgram.Close()
+		turns.stop()
+	}()
+
+	interactor := j.executor.Interact(checkerLimits, toInteractor, turns.relay(fromInteractor, false),
+		fmt.Sprintf("ARGS=%s %s %s", judgeInputFile, interactorLogFile, judgeAnswerFile))
+	fromInteractor.Close()
+	toInteractor.Close()
+	turns.stop()
+	wg.Wait()
+	run.Stdout = sent.String()
+	run.OutputOverflow = sent.overflow
+
+	message := strings.TrimSpace(interactor.Stderr)
+	idle, programIdle := turns.idle()
+	switch {
+	case idle && programIdle:
+		return VerdictTimeLimit, fmt.Sprintf("no answer to the interactor within %d ms (is the output flushed?)", turns.timeout.Milliseconds()), run
+	case idle:
+		return VerdictInternalError, fmt.Sprintf("the interactor did not answer within %d ms", turns.timeout.Milliseconds()), run
+	case interactor.Err != nil:
+		return VerdictInternalError, fmt.Sprintf("interactor: %v", interactor.Err), run
+	}
+	// Writing to an interactor that already hung up is not the program's fault
+	if verdict := classifyRun(run, limits); verdict != "" && run.signal() != "SIGPIPE" {
+		return verdict, message, run
+	}
+	switch interactor.ExitCode {
+	case judgeExitOK:
+		return VerdictAccepted, message, run
+	case judgeExitWrongAnswer, judgeExitPresentErr:
+		return VerdictWrongAnswer, message, run
+	}
+	return VerdictInternalError, fmt.Sprintf("interactor failed (exit code %d): %s", interactor.ExitCode, message), run
+}
+
+// Close removes the interactor's executor
+func (j *interactiveJudge) Close() error {
+	j.executor.Cleanup()
+	return nil
+}
+
+// turnWatch follows whose turn it is in an interaction and ends it when a turn lasts too long,
+// closing both pipes so each side reads the end of its input
+type turnWatch struct {
+	timeout time.Duration
+
+	mu          sync.Mutex
+	last        time.Time   // of the last message
+	programTurn bool        // the interactor spoke last (or nobody did yet), so the program must answer
+	timedOut    bool        // the interaction was ended by the watch
+	pipes       []io.Closer // write ends of both pipes
+	done        chan struct{}
+	stopOnce    sync.Once
+}
+
+func newTurnWatch(timeout time.Duration) *turnWatch {
+	if timeout < minTurnTimeout {
+		timeout = minTurnTimeout
+	}
+	t := &turnWatch{timeout: timeout, last: time.Now().Add(turnStartupGrace), programTurn: true, done: make(chan struct{})}
+	go t.watch()
+	return t
+}
+
+// relay returns a writer to w that records a message of the program (or of the interactor)
+func (t *turnWatch) relay(w *os.File, fromProgram bool) io.Writer {
+	t.mu.Lock()
+	t.pipes = append(t.pipes, w)
+	t.mu.Unlock()
+	return turnWriter{t: t, w: w, fromProgram: fromProgram}
+}
+
+func (t *turnWatch) watch() {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+		}
+		t.mu.Lock()
+		if time.Since(t.last) > t.timeout {
+			t.timedOut = true
+			for _, p := range t.pipes {
+				p.Close()
+			}
+			t.mu.Unlock()
+			return
+		}
+		t.mu.Unlock()
+	}
+}
+
+// stop ends the watch once one of the sides has finished: from then on only the other side's
+// own limits apply
+func (t *turnWatch) stop() {
+	t.stopOnce.Do(func() { close(t.done) })
+}
+
+// idle tells if the interaction was ended by the watch, and if it was waiting for the program
+func (t *turnWatch) idle() (bool, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.timedOut, t.programTurn
+}
+
+type turnWriter struct {
+	t           *turnWatch
+	w           io.Writer
+	fromProgram bool
+}
+
+// Write passes a message on. Once its reader is gone (the other side exited or the watch closed
+// the pipe) it fails, which closes the writer's stdout: like a direct pipe, the writer then gets
+// SIGPIPE or EPIPE, and its exit status still tells how it ended.
+func (tw turnWriter) Write(p []byte) (int, error) {
+	tw.t.mu.Lock()
+	if !tw.t.timedOut { // what is flushed once the watch closed the pipes does not change the blame
+		tw.t.last = time.Now()
+		tw.t.programTurn = !tw.fromProgram
+	}
+	tw.t.mu.Unlock()
+	return tw.w.Write(p)
+}
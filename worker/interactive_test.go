@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Interactor of the tests: it sends the number of the input, the program must answer its double
+const doublingInteractor = `read n < "$1"
+echo "$n"
+read reply
+if [ "$reply" = "$(cat "$3")" ]; then
+	echo "ok $reply"
+	exit 0
+fi
+echo "got $reply" >&2
+exit 1
+`
+
+func TestInteract(t *testing.T) {
+	useLocalExecutor(t)
+	limits := Limits{CPUTime: time.Second, WallTime: 5 * time.Second, MemoryMB: 64}
+	judge, err := newInteractiveJudge("interactor", CheckerSpec{Mode: CheckerInteractive, Language: testLanguage, Code: doublingInteractor})
+	if err != nil {
+		t.Fatalf("newInteractiveJudge: %v", err)
+	}
+	t.Cleanup(func() { judge.Close() })
+
+	tests := []struct {
+		name        string
+		program     string
+		want        Verdict
+		wantMessage string
+	}{
+		{"accepted", "read n\necho $((n * 2))\n", VerdictAccepted, ""},
+		{"wrong answer", "read n\necho $((n + 1))\n", VerdictWrongAnswer, "got 22"},
+		{"idle program", "read n\nsleep 3\n", VerdictTimeLimit, "no answer to the interactor"},
+		// The interactor exits after the answer: the program's writes fail, not the interaction
+		{"writes after the interactor exits", "read n\necho $((n * 2))\nwhile :; do echo more; done\n", VerdictAccepted, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := prepareScript(t, "interact", tt.program, limits)
+			verdict, message, run := judge.Interact(program, limits, "21\n", "42\n")
+			if verdict != tt.want || !strings.Contains(message, tt.wantMessage) {
+				t.Errorf("got %s (%s), want %s with %q; program exit code %d (%v): %s",
+					verdict, message, tt.want, tt.wantMessage, run.ExitCode, run.Err, run.Stderr)
+			}
+			if tt.want == VerdictAccepted && !strings.HasPrefix(run.Stdout, "42\n") {
+				t.Errorf("program sent %q, want the answer first", run.Stdout)
+			}
+		})
+	}
+}
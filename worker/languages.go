@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+
+	"leetcode-clone/registry"
+)
+
+// Language is one entry of the language registry (languages.json), shared with the API
+type Language = registry.Language
+
+// Languages available to jobs, by id
+var languages map[string]Language
+
+// loadLanguages reads the language registry, see registry.Load
+func loadLanguages() error {
+	_, byID, err := registry.Load()
+	if err != nil {
+		return err
+	}
+	languages = byID
+	return nil
+}
+
+// languageEnv returns the environment variables telling the executor how to build and run lang
+func languageEnv(lang Language) []string {
+	return []string{
+		fmt.Sprintf("CODE_LANGUAGE=%s", lang.ID),
+		fmt.Sprintf("SOURCE_FILE=%s", lang.SourceFile),
+		fmt.Sprintf("COMPILE_CMD=%s", lang.Compile),
+		fmt.Sprintf("RUN_CMD=%s", lang.Run),
+	}
+}
+
+// languageEnvArgs returns languageEnv as `docker run -e` flags
+func languageEnvArgs(lang Language) []string {
+	var args []string
+	for _, e := range languageEnv(lang) {
+		args = append(args, "-e", e)
+	}
+	return args
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+)
+
+// Defaults used when neither the problem nor the language registry sets limits
+const (
+	defaultTimeLimitMs   = 5000
+	defaultMemoryLimitMB = 100
+
+	// Processes (and threads) a program may have at once
+	maxProcesses = 50
+
+	// Extra memory given to the container for the executor script / runtime itself
+	executorMemoryOverheadMB = 32
+	// Extra time the worker waits for docker itself before giving up on an exec
+	dockerGracePeriod = 5 * time.Second
+	// Wall time allowed for PHASE=compile
+	compileTimeout = 30 * time.Second
+)
+
+// Status values for runs that hit a resource limit
+const (
+	statusTimeLimit   = "time_limit_exceeded"
+	statusMemoryLimit = "memory_limit_exceeded"
+)
+
+// Exit codes reported by the executors when a limit is hit (or the program is killed)
+const (
+	exitCodeTimeout = 124      // coreutils `timeout` (wall time)
+	exitCodeSIGKILL = 128 + 9  // killed by the OOM killer
+	exitCodeSIGXCPU = 128 + 24 // RLIMIT_CPU (ulimit -t) exceeded
+)
+
+// Limits are the resource limits applied to every test case of a job
+type Limits struct {
+	CPUTime  time.Duration // CPU time the program may use
+	WallTime time.Duration // real time before the program is killed
+	MemoryMB int           // memory available to the program
+}
+
+// limitsFor builds the limits for a job, falling back to the language defaults (playground runs)
+func limitsFor(job Job) Limits {
+	lang := languages[job.Language]
+	timeLimit := firstPositive(job.TimeLimit, lang.TimeLimitMs, defaultTimeLimitMs)
+	memoryLimit := firstPositive(job.MemoryLimit, lang.MemoryLimitMB, defaultMemoryLimitMB)
+
+	cpu := time.Duration(timeLimit) * time.Millisecond
+	return Limits{
+		CPUTime: cpu,
+		// The container only gets half a CPU, so allow twice the CPU time plus startup
+		WallTime: 2*cpu + time.Second,
+		MemoryMB: memoryLimit,
+	}
+}
+
+// firstPositive returns the first value that is set (> 0)
+func firstPositive(values ...int) int {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+	return 0
+}
+
+// dockerArgs returns the `docker run` flags that enforce the memory limit
+func (l Limits) dockerArgs() []string {
+	containerMemory := l.MemoryMB + executorMemoryOverheadMB
+	return []string{
+		fmt.Sprintf("--memory=%dm", containerMemory),
+		fmt.Sprintf("--memory-swap=%dm", containerMemory), // no swap
+	}
+}
+
+// cpuSeconds rounds the CPU time limit up to whole seconds, the only unit `ulimit -t` takes
+func (l Limits) cpuSeconds() int {
+	return int((l.CPUTime + time.Second - 1) / time.Second)
+}
+
+// env returns the environment variables telling the executors which limits to apply
+func (l Limits) env() []string {
+	return []string{
+		fmt.Sprintf("TIMEOUT=%.3f", l.WallTime.Seconds()),
+		fmt.Sprintf("CPU_LIMIT=%d", l.cpuSeconds()),
+		fmt.Sprintf("MEMORY_LIMIT=%d", l.MemoryMB),
+	}
+}
+
+// envArgs returns env as `docker run -e` flags
+func (l Limits) envArgs() []string {
+	var args []string
+	for _, e := range l.env() {
+		args = append(args, "-e", e)
+	}
+	return args
+}
+
+var errWallTimeExceeded = errors.New("wall time limit exceeded")
+
+// runLimited runs a docker command, killing it if it outlives the wall time limit.
+// Its stdout is kept in the outcome, or written to stdout instead if that is not nil.
+func runLimited(l Limits, stdin io.Reader, stdout io.Writer, args ...string) runOutcome {
+	runCtx, cancel := context.WithTimeout(ctx, l.WallTime+dockerGracePeriod)
+	defer cancel()
+
+	var output, stderr cappedBuffer
+	cmd := exec.CommandContext(runCtx, "docker", args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = &output
+	if stdout != nil {
+		cmd.Stdout = stdout
+	}
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	outcome := runOutcome{
+		Stdout:         output.String(),
+		Stderr:         stderr.String(),
+		ExitCode:       exitCodeOf(err),
+		Elapsed:        time.Since(start),
+		OutputOverflow: output.overflow,
+	}
+	// Failing to write the output (the other side of an interaction hung up) is not how the
+	// command exited, as with a direct pipe its output just stopped there
+	if outcome.ExitCode < 0 && cmd.ProcessState != nil {
+		outcome.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if runCtx.Err() == context.DeadlineExceeded {
+		outcome.Err = errWallTimeExceeded
+	} else if outcome.ExitCode < 0 {
+		outcome.Err = err
+	}
+	return outcome
+}
+
+// limitDescription describes the limit behind a TLE/MLE verdict for the result output
+func limitDescription(v Verdict, l Limits) string {
+	if v == VerdictMemoryLimit {
+		return fmt.Sprintf("memory limit (%d MB)", l.MemoryMB)
+	}
+	return fmt.Sprintf("time limit (%d ms)", l.CPUTime.Milliseconds())
+}
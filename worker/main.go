@@ -3,17 +3,15 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
-	"net/http"
 	"os"
-	"os/exec"
 	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
-	"github.com/google/uuid"
 )
 
 var ctx = context.Background()
@@ -23,9 +21,6 @@ var rdb = redis.NewClient(&redis.Options{
 	Addr: os.Getenv("REDIS_ADDR"),
 })
 
-// Map to store code by ID
-var codeStore = make(map[string]string)
-
 // Job represents a code execution job
 type Job struct {
 	ID        string    `json:"id"`
@@ -36,223 +31,242 @@ type Job struct {
 	Outputs   []string  `json:"outputs"`
 	UserID    string    `json:"user_id"`
 	ProblemID  string    `json:"problem_id"`
+	Checker    CheckerSpec `json:"checker"`        // how outputs are compared
+	TimeLimit   int      `json:"time_limit_ms"`   // CPU time per test case, 0 = default
+	MemoryLimit int      `json:"memory_limit_mb"` // memory per test case, 0 = default
+	RunAll     bool      `json:"run_all"`           // keep running after the first failing test
+	Hidden     []bool    `json:"hidden,omitempty"`  // per test: hidden from the user
+	ShowHidden bool      `json:"show_hidden"`       // the user may see hidden tests (admins)
+	Stdin      string    `json:"stdin,omitempty"`   // input of a playground run, none if empty; the API never sends it with tests
+	Mode       string    `json:"mode,omitempty"`    // ModeRun or ModeSubmit, "" for the playground
+	Subtasks   []Subtask `json:"subtasks,omitempty"` // scoring groups of the tests, none = all or nothing
+	Generator     *Program `json:"generator,omitempty"`      // ModeGenerate, ModeStress: writes an input from its arguments
+	Validator     *Program `json:"validator,omitempty"`      // ModeGenerate: checks the inputs, optional
+	GeneratorArgs []string `json:"generator_args,omitempty"` // ModeGenerate: arguments of each test
+	Brute         *Program `json:"brute,omitempty"`          // ModeStress: solution the job's code is compared to
+	Seeds         int      `json:"seeds,omitempty"`          // ModeStress: number of seeds to try
+	Seed          int      `json:"seed,omitempty"`           // ModeStress: first seed, random if 0
+	Function      *FunctionSpec `json:"function,omitempty"`  // function problem: the code is wrapped in a harness, see wrapFunction
 }
 
+// Modes of a job for a problem: run the sample tests, a submission (recorded in the database),
+// the validation of the problem with its reference solution, or a stress test
+const (
+	ModeRun      = "run"
+	ModeSubmit   = "submit"
+	ModeValidate = "validate"
+	ModeGenerate = "generate" // generate test cases, see generateTests
+	ModeStress   = "stress"   // compare the code to a brute-force solution, see stressTest
+)
+
 // JobResult represents the result of a code execution
 type JobResult struct {
 	JobID     string    `json:"job_id"`
 	Status    string    `json:"status"`
 	Output    string    `json:"output"`
 	Error     string    `json:"error"`
-	ExecTime  int64     `json:"exec_time_ms"`
+	ExecTime  int64     `json:"exec_time_ms"`        // wall time of the whole job
+	CPUTime   int64     `json:"cpu_time_ms"`         // CPU time of the program, the most any test used
+	MemoryKB  int64     `json:"memory_kb"`           // peak memory of the program, the most any test used
+	measured  bool      // the executor reported CPUTime and MemoryKB, see usageColumns
 	Timestamp time.Time `json:"timestamp"`
 	TestCases int 		`json:"test_cases"`
 	TotalCases  int 	`json:"total_cases"`
 	UserID    string    `json:"user_id"`
 	ProblemID  string    `json:"problem_id"`
 	Language  string    `json:"language"`
-}
-
-// HTTP handler for serving code files
-func codeHandler(w http.ResponseWriter, r *http.Request) {
-	codeID := r.URL.Query().Get("id")
-	if codeID == "" {
-		http.Error(w, "Code ID is required", http.StatusBadRequest)
-		return
-	}
-
-	code, exists := codeStore[codeID]
-	if !exists {
-		http.Error(w, "Code not found", http.StatusNotFound)
-		return
-	}
-
-	w.Header().Set("Content-Type", "text/plain")
-	w.WriteHeader(http.StatusOK)
-	io.WriteString(w, code)
+	Verdict   Verdict   `json:"verdict"`             // AC, WA, CE, RE, TLE, MLE, OLE, OK or IE
+	ExitCode  int       `json:"exit_code"`           // exit code of the failing (or last) run
+	Signal    string    `json:"signal,omitempty"`    // signal that killed the program, e.g. SIGSEGV
+	Stderr    string    `json:"stderr,omitempty"`    // stderr of the failing run / compiler output
+	Tests     []TestCaseResult `json:"tests,omitempty"` // one entry per test case that was run
+	Score     float64   `json:"score"`               // percentage of the problem points earned (0-100)
+	Subtasks  []SubtaskResult `json:"subtasks,omitempty"` // per-subtask scores
+	Seed      int       `json:"seed,omitempty"`      // ModeStress: first seed tried, to repeat the stress test
 }
 
 // executeCode executes the code in a Docker container
 func executeCode(job Job) JobResult {
-	// Map from language to executor path inside the container
-	execPaths := map[string]string{
-		"python":    "/app/executor.py",
-		"javascript": "/executor/executor.js",
-		"cpp":       "/app/execute.sh",
-		"csharp":    "/app/execute.sh",
-	}
-	execPath, ok := execPaths[job.Language]
-	if !ok {
-		return JobResult{
-			JobID:     job.ID,
-			Status:    "error",
-			Error:     fmt.Sprintf("Unsupported language: %s", job.Language),
-			Timestamp: time.Now(),
-		}
-	}
-
 	startTime := time.Now()
+	limits := limitsFor(job)
 
-	// Store code for HTTP server (so executors can do an HTTP GET)
-	codeID := uuid.New().String()
-	codeStore[codeID] = job.Code
-	defer delete(codeStore, codeID)
+	// “validate” == we have multiple Inputs/Outputs (a submission with test cases)
+	validate := len(job.Inputs) > 0 && len(job.Outputs) > 0
 
-	// Determine worker‐host and port (for CODE_URL)
-	workerHost := os.Getenv("WORKER_HOST")
-	if workerHost == "" {
-		workerHost = "worker"
+	// result fills in the fields every JobResult shares
+	result := func(verdict Verdict, outcome runOutcome, passed int) JobResult {
+		return JobResult{
+			JobID:      job.ID,
+			Status:     statusFor(verdict, validate),
+			ExecTime:   time.Since(startTime).Milliseconds(),
+			CPUTime:    outcome.CPUTime.Milliseconds(),
+			MemoryKB:   outcome.MemoryKB,
+			measured:   outcome.UsageMeasured,
+			Timestamp:  time.Now(),
+			TestCases:  passed,
+			TotalCases: len(job.Inputs),
+			UserID:     job.UserID,
+			ProblemID:  job.ProblemID,
+			Language:   job.Language,
+			Verdict:    verdict,
+			ExitCode:   outcome.ExitCode,
+			Signal:     outcome.signal(),
+			Stderr:     outcome.Stderr,
+		}
 	}
-	workerPort := os.Getenv("WORKER_PORT")
-	if workerPort == "" {
-		workerPort = "8081"
+	internalError := func(err error) JobResult {
+		res := result(VerdictInternalError, runOutcome{}, 0)
+		res.Error = err.Error()
+		return res
 	}
 
-	// Pick the executor image
-	var containerImage string
-	switch job.Language {
-	case "python":
-		containerImage = "python-executor:latest"
-	case "javascript":
-		containerImage = "javascript-executor:latest"
-	case "cpp":
-		containerImage = "cpp-executor:latest"
-	case "csharp":
-		containerImage = "csharp-executor:latest"
-	default:
-		return JobResult{
-			JobID:     job.ID,
-			Status:    "error",
-			Error:     fmt.Sprintf("Unsupported language: %s", job.Language),
-			Timestamp: time.Now(),
+	// 1) Start one detached executor container, reused for compiling and every test
+	setStage(job.ID, StageCompiling, 0, 0)
+	code, err := programCode(job, job.Language, job.Code)
+	if err != nil {
+		return internalError(err)
+	}
+	executor, err := prepareExecutor(job.ID, job.Language, code, limits)
+	if err != nil {
+		if errors.Is(err, errUnsupportedLanguage) {
+			err = fmt.Errorf("Unsupported language: %s", job.Language)
 		}
+		return internalError(err)
+	}
+	defer executor.Cleanup()
+
+	// 2) Compile once (download + syntax check for interpreted languages)
+	if compile := executor.Compile(); compile.Err != nil || compile.ExitCode != 0 {
+		verdict, message := compileFailure(compile)
+		res := result(verdict, compile, 0)
+		res.Error = message
+		return res
 	}
 
-	// “validate” == we have multiple Inputs/Outputs (a submission with test cases)
-	validate := len(job.Inputs) > 0 && len(job.Outputs) > 0
-
-	if validate {
-		
-		// 2) Start one detached executor container (it sees /code as the shared volume)
-		containerID := fmt.Sprintf("code-exec-%s", job.ID)
-		_ = exec.Command("docker", "rm", "-f", containerID).Run() // best‐effort cleanup
-
-		dockerRunArgs := []string{
-			"run", "-d",
-			"--name", containerID,
-			"--network=code-execution-service_default",
-			"--memory=100m", "--cpus=0.5", "--pids-limit=50",
-			"-e", fmt.Sprintf("CODE_URL=http://%s:%s/code?id=%s", workerHost, workerPort, codeID),
-			"-e", fmt.Sprintf("CODE_LANGUAGE=%s", job.Language),
-			containerImage,
+	// 3a) Playground: a single run without expected output, with the custom input if any
+	if !validate {
+		setStage(job.ID, StageRunning, 1, 0)
+		var run runOutcome
+		if job.Stdin != "" {
+			run = executor.Run(limits, strings.NewReader(job.Stdin))
+		} else {
+			run = executor.Run(limits, nil, "SINGLE=1")
 		}
-		if err := exec.Command("docker", dockerRunArgs...).Run(); err != nil {
-			return JobResult{
-				JobID:     job.ID,
-				Status:    "error",
-				Error:     fmt.Sprintf("Failed to start executor container: %v", err),
-				Timestamp: time.Now(),
-			}
+		verdict := classifyRun(run, limits)
+		switch verdict {
+		case "":
+			res := result(VerdictOK, run, 0)
+			res.Output = run.Stdout
+			return res
+		case VerdictTimeLimit, VerdictMemoryLimit:
+			res := result(verdict, run, 0)
+			res.Output = run.Stdout
+			res.Error = fmt.Sprintf("Code execution exceeded the %s", limitDescription(verdict, limits))
+			return res
 		}
-		defer exec.Command("docker", "rm", "-f", containerID).Run()
-
-		for i, input := range job.Inputs {
-
-			execCmd := exec.Command(
-				"docker", "exec", "-i", // Add -i flag for interactive stdin
-				"-e", fmt.Sprintf("CODE_URL=http://%s:%s/code?id=%s", workerHost, workerPort, codeID),
-				"-e", fmt.Sprintf("CODE_LANGUAGE=%s", job.Language),
-				containerID,
-				execPath,
-			)
-			
-			// Provide input via stdin
-			execCmd.Stdin = strings.NewReader(input)
-			
-			outputBytes, err := execCmd.CombinedOutput()
-			actual := strings.TrimSpace(string(outputBytes))
-			expected := strings.TrimSpace(job.Outputs[i])
+		res := result(verdict, run, 0)
+		res.Output = run.Stdout
+		res.Error = fmt.Sprintf("Execution error: exit code %d\nOutput: %s", run.ExitCode, run.Stdout+run.Stderr)
+		return res
+	}
 
-			if err != nil || actual != expected {
-				exec.Command("docker", "rm", "-f", containerID).Run()
-				return JobResult{
-					JobID:     job.ID,
-					Status:    "fail",
-					Output:    fmt.Sprintf("Test #%d failed\nInput: %q\nExpected: %q\nGot: %q", i+1, input, expected, actual),
-					ExecTime:  time.Since(startTime).Milliseconds(),
-					Timestamp: time.Now(),
-					TestCases: i + 1,
-					TotalCases: len(job.Inputs),
-					UserID:    job.UserID,
-					ProblemID: job.ProblemID,
-					Language:  job.Language,
-				}
-			}
+	// The output checker, which may need its own sandbox; interactive problems have an interactor instead
+	var checker Checker
+	var interactor *interactiveJudge
+	if job.Checker.Mode == CheckerInteractive {
+		interactor, err = newInteractiveJudge(job.ID+"-interactor", job.Checker)
+		if err != nil {
+			return internalError(err)
+		}
+		defer interactor.Close()
+	} else {
+		checker, err = newChecker(job)
+		if err != nil {
+			return internalError(err)
+		}
+		if closer, ok := checker.(io.Closer); ok {
+			defer closer.Close()
 		}
-
-    	// All tests passed
-    	return JobResult{
-			JobID:     job.ID,
-			Status:    "accept",
-			Output:    "All tests passed.",
-			ExecTime:  time.Since(startTime).Milliseconds(),
-			Timestamp: time.Now(),
-			TestCases: len(job.Inputs),
-			TotalCases: len(job.Inputs),
-			UserID:    job.UserID,
-			ProblemID: job.ProblemID,
-			Language:  job.Language,
-    	}
 	}
 
+	// 3b) Submission: run the test cases, stopping at the first failure unless RunAll is set
+	// (with subtasks every test counts towards the score, so they all run)
+	runAll := job.RunAll || len(job.Subtasks) > 0
+	var tests []TestCaseResult
+	firstFailure := -1 // index in tests of the first failing test
+	var failedRun runOutcome
+	passed := 0
+	for i, input := range job.Inputs {
+		// Provide input via stdin, or let the interactor talk to the program
+		setStage(job.ID, StageRunning, i+1, len(job.Inputs))
+		var run runOutcome
+		var verdict Verdict
+		message := ""
+		if interactor != nil {
+			verdict, message, run = interactor.Interact(executor, limits, input, job.Outputs[i])
+		} else {
+			run = executor.Run(limits, strings.NewReader(input))
+			verdict = classifyRun(run, limits)
+			if verdict == "" {
+				verdict, message = checker.Check(input, job.Outputs[i], run.Stdout)
+			}
+		}
 
-	
-	dockerArgs := []string{
-		"run", "--rm",
-		"--entrypoint", execPath,
-		"--network=code-execution-service_default",
-		"--memory=100m", "--cpus=0.5", "--pids-limit=50",
-		"-e", fmt.Sprintf("CODE_URL=http://%s:%s/code?id=%s", workerHost, workerPort, codeID),
-		"-e", fmt.Sprintf("CODE_LANGUAGE=%s", job.Language),
-		"-e", "SINGLE=1", // Much clearer!
-		"-v", "shared-code:/code",
-		containerImage,
-	}
-
-	outputBytes, err := exec.Command("docker", dockerArgs...).CombinedOutput()
-	execTime := time.Since(startTime).Milliseconds()
-	if err != nil {
-		if execTime >= 5000 {
-			return JobResult{
-				JobID:     job.ID,
-				Status:    "timeout",
-				Error:     "Code execution timed out",
-				ExecTime:  execTime,
-				Timestamp: time.Now(),
+		tc := newTestCaseResult(job, i, verdict, run)
+		if !tc.Hidden || job.ShowHidden {
+			tc.Message = truncate(message)
+		}
+		tests = append(tests, tc)
+		publishEvent(JobEvent{Type: EventTest, JobID: job.ID, Test: i + 1, Total: len(job.Inputs), Case: &tc})
+		if verdict == VerdictAccepted {
+			passed++
+			continue
+		}
+		if firstFailure < 0 {
+			firstFailure = len(tests) - 1
+			failedRun = run
+			if run.Err != nil && verdict == VerdictInternalError {
+				failedRun.Stderr = strings.TrimSpace(run.Err.Error() + "\n" + run.Stderr)
 			}
 		}
-		return JobResult{
-			JobID:     job.ID,
-			Status:    "error",
-			Error:     fmt.Sprintf("Execution error: %v\nOutput: %s", err, string(outputBytes)),
-			ExecTime:  execTime,
-			Timestamp: time.Now(),
+		if !runAll {
+			break
 		}
 	}
-
-	return JobResult{
-		JobID:     job.ID,
-		Status:    "success",
-		Output:    string(outputBytes),
-		ExecTime:  execTime,
-		Timestamp: time.Now(),
+	score, subtasks := scoreTests(job, tests)
+
+	if firstFailure >= 0 {
+		failed := tests[firstFailure]
+		res := result(failed.Verdict, failedRun, passed)
+		res.Output = failed.describe(limits)
+		if runAll {
+			res.Output = fmt.Sprintf("Passed %d/%d tests.\n%s", passed, len(job.Inputs), res.Output)
+		}
+		if len(subtasks) > 0 {
+			res.Output = fmt.Sprintf("Score: %.2f/100.\n%s", score, res.Output)
+		}
+		if failed.Hidden && !job.ShowHidden {
+			res.Stderr = ""
+		}
+		res.Tests = tests
+		res.CPUTime, res.MemoryKB, res.measured = peakUsage(tests)
+		res.Score, res.Subtasks = score, subtasks
+		return res
 	}
+
+	// All tests passed
+	res := result(VerdictAccepted, runOutcome{}, len(job.Inputs))
+	res.Output = "All tests passed."
+	res.Tests = tests
+	res.CPUTime, res.MemoryKB, res.measured = peakUsage(tests)
+	res.Score, res.Subtasks = score, subtasks
+	return res
 }
 
-func processJobs() {
+func processJobs(c *consumer) {
 	for {
-		// Pop job from Redis queue with timeout
-		result, err := rdb.BRPop(ctx, 5*time.Second, "code_jobs").Result()
+		// Move the next job into this consumer's processing list, so it survives a crash
+		payload, err := c.next(5 * time.Second)
 		if err != nil {
 			if err == redis.Nil {
 				// No jobs available, continue polling
@@ -265,30 +279,53 @@ func processJobs() {
 
 		// Parse job data
 		var job Job
-		if err := json.Unmarshal([]byte(result[1]), &job); err != nil {
-			log.Printf("Error parsing job data: %v", err)
+		if err := json.Unmarshal([]byte(payload), &job); err != nil {
+			log.Printf("Error parsing job data, moving it to %s: %v", deadLetterList, err)
+			rdb.LMove(ctx, c.processingList(), deadLetterList, "LEFT", "LEFT")
 			continue
 		}
 
 		log.Printf("Processing job %s (Language: %s)", job.ID, job.Language)
-
-		// Execute code
-		jobResult := executeCode(job)
-
-		// Store result in Redis
-		resultData, err := json.Marshal(jobResult)
-		if err != nil {
-			log.Printf("Error marshaling result data: %v", err)
+		rdb.ZRem(ctx, queuePositions, job.ID)
+		setStage(job.ID, StageDequeued, 0, 0)
+
+		// Execute code, store the submission and then publish the result
+		var jobResult JobResult
+		switch job.Mode {
+		case ModeGenerate:
+			jobResult = generateTests(job)
+		case ModeStress:
+			jobResult = stressTest(job)
+		default:
+			jobResult = executeCode(job)
+		}
+		if err := recordSubmission(job, jobResult); err != nil {
+			// Not acknowledged: the job runs again rather than losing the submission
+			log.Printf("Error storing submission of job %s: %v", job.ID, err)
+			c.retry(payload, job)
 			continue
 		}
+		recordValidation(job, jobResult)
+		storeResult(job, jobResult)
+		setStage(job.ID, StageDone, 0, 0)
+		c.done(payload, job.ID)
+	}
+}
 
-		// Store result with expiration (24 hours)
-		if err := rdb.Set(ctx, "result:"+job.ID, resultData, 24*time.Hour).Err(); err != nil {
-			log.Printf("Error storing result in Redis: %v", err)
-		} else {
-			log.Printf("Result stored in Redis for job %s (status: %s)", job.ID, jobResult.Status)
-		}
+// storeResult saves the result of a job in Redis for 24 hours and tells the streaming clients
+func storeResult(job Job, jobResult JobResult) {
+	resultData, err := json.Marshal(jobResult)
+	if err != nil {
+		log.Printf("Error marshaling result data: %v", err)
+		return
+	}
+
+	if err := rdb.Set(ctx, "result:"+job.ID, resultData, 24*time.Hour).Err(); err != nil {
+		log.Printf("Error storing result in Redis: %v", err)
+	} else {
+		log.Printf("Result stored in Redis for job %s (status: %s)", job.ID, jobResult.Status)
 	}
+	publishEvent(JobEvent{Type: EventResult, JobID: job.ID, Result: &jobResult})
 }
 
 func main() {
@@ -299,26 +336,21 @@ func main() {
 
 	log.Println("Starting code execution worker...")
 
-	// Start HTTP server for code serving
-	http.HandleFunc("/code", codeHandler)
-
-	port := os.Getenv("WORKER_PORT")
-	if port == "" {
-		port = "8081"
+	if err := loadLanguages(); err != nil {
+		log.Fatalf("Failed to load languages: %v", err)
 	}
-	go func() {
-		log.Printf("Starting HTTP server on port %s", port)
-		if err := http.ListenAndServe(":"+port, nil); err != nil {
-			log.Fatalf("HTTP server error: %v", err)
-		}
-	}()
+
+	connectToDB()
 
 	// Start multiple worker goroutines to handle concurrent jobs
 	numWorkers := 5
 	for i := 0; i < numWorkers; i++ {
-		go processJobs()
+		go processJobs(newConsumer())
 	}
 
+	// Give the jobs of crashed workers back to the queue
+	go reapJobs()
+
 	// Keep the main goroutine alive
 	select {}
 }
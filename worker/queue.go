@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// Redis keys of the job queue. The API pushes jobs on the left of jobQueue; each consumer
+// moves the job it takes into its own processing list until the result is stored.
+const (
+	jobQueue           = "code_jobs"
+	processingListKey  = "code_jobs:processing:" // + consumer
+	consumerSet        = "code_jobs:consumers"
+	heartbeatKeyPrefix = "code_jobs:heartbeat:" // + consumer, expires when the consumer dies
+	attemptsHash       = "code_jobs:attempts"   // job id -> times the job was lost or could not be stored
+	deadLetterList     = "code_jobs:dead"       // jobs given up on, for admins to inspect
+	reaperLock         = "code_jobs:reaper"
+)
+
+const (
+	heartbeatInterval = 10 * time.Second
+	heartbeatTTL      = 30 * time.Second
+	reapInterval      = 15 * time.Second
+	// Times a job may be lost (its consumer died) or fail to be stored before it goes to the
+	// dead-letter list
+	maxAttempts = 3
+)
+
+// consumer is one job-processing goroutine, known to Redis by its id
+type consumer struct {
+	id string
+}
+
+// newConsumer registers a consumer and starts its heartbeat
+func newConsumer() *consumer {
+	hostname, _ := os.Hostname()
+	c := &consumer{id: fmt.Sprintf("%s-%s", hostname, uuid.New().String()[:8])}
+
+	c.beat()
+	if err := rdb.SAdd(ctx, consumerSet, c.id).Err(); err != nil {
+		log.Printf("Error registering consumer %s: %v", c.id, err)
+	}
+	go func() {
+		for range time.Tick(heartbeatInterval) {
+			c.beat()
+		}
+	}()
+	return c
+}
+
+func (c *consumer) processingList() string {
+	return processingListKey + c.id
+}
+
+func (c *consumer) beat() {
+	if err := rdb.Set(ctx, heartbeatKeyPrefix+c.id, time.Now().Unix(), heartbeatTTL).Err(); err != nil {
+		log.Printf("Error refreshing heartbeat of %s: %v", c.id, err)
+	}
+}
+
+// next blocks until a job is available (or timeout) and moves it into the processing list.
+// It returns redis.Nil on timeout.
+func (c *consumer) next(timeout time.Duration) (string, error) {
+	return rdb.BLMove(ctx, jobQueue, c.processingList(), "RIGHT", "LEFT", timeout).Result()
+}
+
+// done removes a finished job from the processing list
+func (c *consumer) done(payload, jobID string) {
+	if err := rdb.LRem(ctx, c.processingList(), 1, payload).Err(); err != nil {
+		log.Printf("Error acknowledging job %s: %v", jobID, err)
+	}
+	rdb.HDel(ctx, attemptsHash, jobID)
+}
+
+// retry gives a job whose submission could not be stored back to the queue, first in line, or
+// moves it to the dead-letter list once it failed maxAttempts times. If Redis fails too, the job
+// stays in the processing list and is requeued when this consumer is gone.
+func (c *consumer) retry(payload string, job Job) {
+	attempts, err := rdb.HIncrBy(ctx, attemptsHash, job.ID, 1).Result()
+	if err != nil {
+		log.Printf("Error counting attempts of job %s, leaving it in %s: %v", job.ID, c.processingList(), err)
+		return
+	}
+	abandon := attempts >= maxAttempts
+
+	// Out of the processing list and back in a queue in one step, so the job is never in both
+	_, err = rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LRem(ctx, c.processingList(), 1, payload)
+		if abandon {
+			pipe.LPush(ctx, deadLetterList, payload)
+		} else {
+			pipe.RPush(ctx, jobQueue, payload)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error requeueing job %s, leaving it in %s: %v", job.ID, c.processingList(), err)
+		return
+	}
+
+	if abandon {
+		log.Printf("Job %s failed %d times, moving it to %s", job.ID, attempts, deadLetterList)
+		abandonJob(job, attempts)
+		return
+	}
+	log.Printf("Requeueing job %s (attempt %d)", job.ID, attempts+1)
+	rdb.ZAdd(ctx, queuePositions, &redis.Z{Score: 0, Member: job.ID}) // first in line
+	setStage(job.ID, StageQueued, 0, 0)
+}
+
+// reapJobs periodically gives the jobs of dead consumers back to the queue
+func reapJobs() {
+	for range time.Tick(reapInterval) {
+		reapOnce()
+	}
+}
+
+// reapOnce gives the jobs of dead consumers back to the queue, unless another worker process
+// reaped less than reapInterval ago (one reaper at a time). It reports whether it reaped.
+func reapOnce() bool {
+	locked, err := rdb.SetNX(ctx, reaperLock, 1, reapInterval).Result()
+	if err != nil || !locked {
+		return false
+	}
+	consumers, err := rdb.SMembers(ctx, consumerSet).Result()
+	if err != nil {
+		log.Printf("Error listing consumers: %v", err)
+		return true
+	}
+	for _, id := range consumers {
+		alive, err := rdb.Exists(ctx, heartbeatKeyPrefix+id).Result()
+		if err != nil || alive > 0 {
+			continue
+		}
+		if requeueJobs(id) {
+			rdb.SRem(ctx, consumerSet, id)
+		}
+	}
+	return true
+}
+
+// requeueJobs moves the jobs of a dead consumer back to the queue, or to the dead-letter list
+// once they were lost maxAttempts times. It reports whether the processing list is now empty.
+func requeueJobs(consumerID string) bool {
+	list := processingListKey + consumerID
+	for {
+		payload, err := rdb.LIndex(ctx, list, -1).Result()
+		if err == redis.Nil {
+			return true
+		}
+		if err != nil {
+			log.Printf("Error reading jobs of consumer %s: %v", consumerID, err)
+			return false
+		}
+
+		var job Job
+		if err := json.Unmarshal([]byte(payload), &job); err != nil {
+			log.Printf("Error parsing job of consumer %s, moving it to %s: %v", consumerID, deadLetterList, err)
+			rdb.LMove(ctx, list, deadLetterList, "RIGHT", "LEFT")
+			continue
+		}
+
+		attempts, err := rdb.HIncrBy(ctx, attemptsHash, job.ID, 1).Result()
+		if err != nil {
+			log.Printf("Error counting attempts of job %s: %v", job.ID, err)
+			return false
+		}
+		if attempts >= maxAttempts {
+			log.Printf("Job %s was lost %d times, moving it to %s", job.ID, attempts, deadLetterList)
+			rdb.LMove(ctx, list, deadLetterList, "RIGHT", "LEFT")
+			abandonJob(job, attempts)
+			continue
+		}
+
+		// Back on the consuming end of the queue, so it is picked up next
+		log.Printf("Requeueing job %s of dead consumer %s (attempt %d)", job.ID, consumerID, attempts+1)
+		if err := rdb.LMove(ctx, list, jobQueue, "RIGHT", "RIGHT").Err(); err != nil {
+			log.Printf("Error requeueing job %s: %v", job.ID, err)
+			return false
+		}
+		rdb.ZAdd(ctx, queuePositions, &redis.Z{Score: 0, Member: job.ID}) // first in line
+		setStage(job.ID, StageQueued, 0, 0)
+	}
+}
+
+// abandonJob tells the clients of a job moved to the dead-letter list that it failed
+func abandonJob(job Job, attempts int64) {
+	storeResult(job, JobResult{
+		JobID:     job.ID,
+		Status:    statusFor(VerdictInternalError, false),
+		Verdict:   VerdictInternalError,
+		Error:     fmt.Sprintf("The job was abandoned after %d attempts", attempts),
+		Timestamp: time.Now(),
+		UserID:    job.UserID,
+		ProblemID: job.ProblemID,
+		Language:  job.Language,
+	})
+	setStage(job.ID, StageDone, 0, 0)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// withRedis points the Redis client at an in-memory Redis for the test
+func withRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	saved := rdb
+	rdb = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		rdb.Close()
+		rdb = saved
+	})
+	return mr
+}
+
+// queuedJob returns the payload of a job, as the API pushes it
+func queuedJob(t *testing.T, id string) (string, Job) {
+	t.Helper()
+	job := Job{ID: id, Mode: ModeSubmit, Language: "python", Code: "print(1)", UserID: "u1", ProblemID: "p1"}
+	payload, err := json.Marshal(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(payload), job
+}
+
+// addConsumer registers a consumer holding payloads in its processing list, alive or dead
+func addConsumer(t *testing.T, mr *miniredis.Miniredis, id string, alive bool, payloads ...string) *consumer {
+	t.Helper()
+	c := &consumer{id: id}
+	mr.SAdd(consumerSet, id)
+	if alive {
+		c.beat()
+	}
+	for _, payload := range payloads {
+		mr.Lpush(c.processingList(), payload)
+	}
+	return c
+}
+
+// listOf returns a Redis list, empty if it does not exist
+func listOf(mr *miniredis.Miniredis, key string) []string {
+	if !mr.Exists(key) {
+		return nil
+	}
+	list, _ := mr.List(key)
+	return list
+}
+
+// wantAbandoned checks the IE result and the stage of a job moved to the dead-letter list
+func wantAbandoned(t *testing.T, mr *miniredis.Miniredis, job Job, attempts string) {
+	t.Helper()
+	data, err := mr.Get("result:" + job.ID)
+	if err != nil {
+		t.Fatalf("no result stored for job %s: %v", job.ID, err)
+	}
+	var res JobResult
+	if err := json.Unmarshal([]byte(data), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Verdict != VerdictInternalError || !strings.Contains(res.Error, "abandoned after "+attempts+" attempts") {
+		t.Errorf("result %q (%s), want IE after %s attempts", res.Verdict, res.Error, attempts)
+	}
+	if stage := mr.HGet("job:"+job.ID, "stage"); stage != StageDone {
+		t.Errorf("stage %q, want %q", stage, StageDone)
+	}
+}
+
+func TestReapJobs(t *testing.T) {
+	mr := withRedis(t)
+	deadPayload, deadJob := queuedJob(t, "lost")
+	livePayload, _ := queuedJob(t, "running")
+	dead := addConsumer(t, mr, "dead", false, deadPayload)
+	live := addConsumer(t, mr, "live", true, livePayload)
+
+	if !reapOnce() {
+		t.Fatal("the first reaper did not get the lock")
+	}
+	if queue := listOf(mr, jobQueue); len(queue) != 1 || queue[0] != deadPayload {
+		t.Errorf("queue %q, want the lost job once", queue)
+	}
+	if list := listOf(mr, dead.processingList()); len(list) != 0 {
+		t.Errorf("processing list of the dead consumer %q, want it empty", list)
+	}
+	if list := listOf(mr, live.processingList()); len(list) != 1 {
+		t.Errorf("processing list of the live consumer %q, want its job", list)
+	}
+	if ok, _ := mr.SIsMember(consumerSet, "dead"); ok {
+		t.Error("the dead consumer is still registered")
+	}
+	if attempts := mr.HGet(attemptsHash, deadJob.ID); attempts != "1" {
+		t.Errorf("attempts %q, want 1", attempts)
+	}
+	if stage := mr.HGet("job:"+deadJob.ID, "stage"); stage != StageQueued {
+		t.Errorf("stage %q, want %q", stage, StageQueued)
+	}
+	if score, err := mr.ZScore(queuePositions, deadJob.ID); err != nil || score != 0 {
+		t.Errorf("queue position %v (%v), want first in line", score, err)
+	}
+
+	// Another worker process reaping right after: the lock keeps it out
+	if reapOnce() {
+		t.Error("a second reaper got the lock")
+	}
+	// Next round: nothing left to requeue, the job is not queued twice
+	mr.FastForward(reapInterval)
+	if !reapOnce() {
+		t.Fatal("the lock did not expire")
+	}
+	if queue := listOf(mr, jobQueue); len(queue) != 1 {
+		t.Errorf("queue %q after a second round, want the lost job once", queue)
+	}
+}
+
+func TestReapJobsDeadLetter(t *testing.T) {
+	mr := withRedis(t)
+	payload, job := queuedJob(t, "unlucky")
+	addConsumer(t, mr, "dead", false, payload, "not json")
+	mr.HSet(attemptsHash, job.ID, "2") // lost twice already
+
+	reapOnce()
+	if queue := listOf(mr, jobQueue); len(queue) != 0 {
+		t.Errorf("queue %q, want it empty", queue)
+	}
+	dead := listOf(mr, deadLetterList)
+	if len(dead) != 2 || dead[0] != "not json" || dead[1] != payload {
+		t.Errorf("dead-letter list %q, want the unparsable job and the job lost 3 times", dead)
+	}
+	wantAbandoned(t, mr, job, "3")
+}
+
+func TestConsumerRetry(t *testing.T) {
+	mr := withRedis(t)
+	payload, job := queuedJob(t, "unstored")
+	c := addConsumer(t, mr, "c", true)
+	mr.Lpush(jobQueue, payload)
+
+	for attempt := 1; attempt < maxAttempts; attempt++ {
+		got, err := c.next(time.Second)
+		if err != nil || got != payload {
+			t.Fatalf("attempt %d: next = %q, %v", attempt, got, err)
+		}
+		c.retry(payload, job)
+		if queue := listOf(mr, jobQueue); len(queue) != 1 || queue[0] != payload {
+			t.Fatalf("attempt %d: queue %q, want the job back once", attempt, queue)
+		}
+		if list := listOf(mr, c.processingList()); len(list) != 0 {
+			t.Fatalf("attempt %d: processing list %q, want it empty", attempt, list)
+		}
+	}
+
+	if _, err := c.next(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	c.retry(payload, job)
+	if queue := listOf(mr, jobQueue); len(queue) != 0 {
+		t.Errorf("queue %q after %d attempts, want it empty", queue, maxAttempts)
+	}
+	if dead := listOf(mr, deadLetterList); len(dead) != 1 || dead[0] != payload {
+		t.Errorf("dead-letter list %q, want the job", dead)
+	}
+	wantAbandoned(t, mr, job, "3")
+}
+
+func TestConsumerDone(t *testing.T) {
+	mr := withRedis(t)
+	payload, job := queuedJob(t, "finished")
+	c := addConsumer(t, mr, "c", true, payload)
+	mr.HSet(attemptsHash, job.ID, "1")
+
+	c.done(payload, job.ID)
+	if list := listOf(mr, c.processingList()); len(list) != 0 {
+		t.Errorf("processing list %q, want it empty", list)
+	}
+	if mr.HGet(attemptsHash, job.ID) != "" {
+		t.Error("the attempts of a finished job are kept")
+	}
+	// Its consumer dying later does not bring the job back
+	mr.Del(heartbeatKeyPrefix + c.id)
+	reapOnce()
+	if queue := listOf(mr, jobQueue); len(queue) != 0 {
+		t.Errorf("queue %q, want it empty", queue)
+	}
+}
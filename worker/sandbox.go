@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+
+	"leetcode-clone/registry"
+)
+
+// Defaults of the container profile, for languages that do not set them
+const (
+	defaultSandboxUser       = "65534:65534" // nobody:nogroup
+	defaultSandboxTmpfsMB    = 64
+	defaultSandboxFileSizeMB = 64
+	defaultSandboxOpenFiles  = 256
+)
+
+// The only writable place in a read-only executor container, holding the work directory
+const dockerTmpDir = "/tmp"
+
+// Sandbox is the container profile of a language (the "sandbox" object in languages.json)
+type Sandbox = registry.Sandbox
+
+// sandboxReadOnly tells whether the root filesystem of profile s is read-only
+func sandboxReadOnly(s Sandbox) bool {
+	return s.ReadOnly == nil || *s.ReadOnly
+}
+
+// sandboxArgs returns the `docker run` flags that apply profile s: executor containers never have
+// network, drop every capability and cannot gain privileges; unset fields take the defaults
+func sandboxArgs(s Sandbox) []string {
+	user := s.User
+	if user == "" {
+		user = defaultSandboxUser
+	}
+	fileSize := firstPositive(s.FileSizeMB, defaultSandboxFileSizeMB)
+	openFiles := firstPositive(s.OpenFiles, defaultSandboxOpenFiles)
+
+	args := []string{
+		"--network=none",
+		"--cap-drop=ALL",
+		"--security-opt=no-new-privileges",
+		"--user", user,
+		"--ulimit", fmt.Sprintf("fsize=%d", fileSize*1024*1024), // bytes
+		"--ulimit", fmt.Sprintf("nofile=%d:%d", openFiles, openFiles),
+	}
+	if s.Seccomp != "" {
+		args = append(args, "--security-opt", "seccomp="+s.Seccomp)
+	}
+	if sandboxReadOnly(s) {
+		// exec, so compiled programs can run from the work directory
+		tmpfs := fmt.Sprintf("%s:rw,exec,nosuid,nodev,size=%dm", dockerTmpDir, firstPositive(s.TmpfsMB, defaultSandboxTmpfsMB))
+		args = append(args, "--read-only", "--tmpfs", tmpfs, "-e", "HOME="+dockerTmpDir)
+	}
+	return args
+}
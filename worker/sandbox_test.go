@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSandboxArgs(t *testing.T) {
+	writable := false
+	tests := []struct {
+		name    string
+		sandbox Sandbox
+		want    []string
+	}{
+		{
+			"defaults",
+			Sandbox{},
+			[]string{
+				"--network=none", "--cap-drop=ALL", "--security-opt=no-new-privileges",
+				"--user", "65534:65534",
+				"--ulimit", "fsize=67108864",
+				"--ulimit", "nofile=256:256",
+				"--read-only", "--tmpfs", "/tmp:rw,exec,nosuid,nodev,size=64m", "-e", "HOME=/tmp",
+			},
+		},
+		{
+			"overrides",
+			Sandbox{User: "1000:1000", TmpfsMB: 128, Seccomp: "/etc/seccomp/java.json", FileSizeMB: 16, OpenFiles: 1024},
+			[]string{
+				"--network=none", "--cap-drop=ALL", "--security-opt=no-new-privileges",
+				"--user", "1000:1000",
+				"--ulimit", "fsize=16777216",
+				"--ulimit", "nofile=1024:1024",
+				"--security-opt", "seccomp=/etc/seccomp/java.json",
+				"--read-only", "--tmpfs", "/tmp:rw,exec,nosuid,nodev,size=128m", "-e", "HOME=/tmp",
+			},
+		},
+		{
+			"writable root filesystem",
+			Sandbox{ReadOnly: &writable, TmpfsMB: 128},
+			[]string{
+				"--network=none", "--cap-drop=ALL", "--security-opt=no-new-privileges",
+				"--user", "65534:65534",
+				"--ulimit", "fsize=67108864",
+				"--ulimit", "nofile=256:256",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sandboxArgs(tt.sandbox); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sandboxArgs(%+v) =\n%q\nwant\n%q", tt.sandbox, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// Stages of a job, kept in the `stage` field of the job:<id> hash for GET /result/{id}
+const (
+	StageQueued    = "queued"    // waiting in code_jobs (set by the API, or on requeue)
+	StageDequeued  = "dequeued"  // taken by a worker
+	StageCompiling = "compiling" // executor starting and compiling
+	StageRunning   = "running"   // running test `test` of `total` (total 0 for playground runs)
+	StageDone      = "done"      // result:<id> is stored
+)
+
+// Sorted set of the queued job ids by arrival, for queue positions (see the API)
+const queuePositions = "code_jobs:positions"
+
+// How long job:<id> is kept, same as the result
+const jobStatusTTL = 24 * time.Hour
+
+// setStage records the stage a job has reached in its job:<id> hash.
+// test and total only matter for StageRunning.
+func setStage(jobID, stage string, test, total int) {
+	key := "job:" + jobID
+	pipe := rdb.TxPipeline()
+	pipe.HSet(ctx, key,
+		"stage", stage,
+		"test", test,
+		"total", total,
+		"updated_at", time.Now().Format(time.RFC3339),
+	)
+	pipe.Expire(ctx, key, jobStatusTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Error updating stage of job %s: %v", jobID, err)
+	}
+	publishEvent(JobEvent{Type: EventStage, JobID: jobID, Stage: stage, Test: test, Total: total})
+}
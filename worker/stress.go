@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Number of seeds of a stress test: the default, the largest accepted, and how long the
+// seeds may take in total (the stress test stops there and reports the seeds it tried). A stress
+// test holds a worker slot like a submission does, so it gets about as long as a slow submission.
+const (
+	defaultStressSeeds = 100
+	maxStressSeeds     = 1000
+	stressTimeBudget   = 30 * time.Second
+)
+
+// Largest first seed of a stress test, the random ones are drawn up to it
+const maxStressSeed = 1000000000
+
+// stressTest runs a stress test job (ModeStress): for seeds s, s+1, ... from the job's first seed
+// (random unless it sets one) the generator writes an input (`generator <seed>`), the brute-force
+// solution computes the expected output and the job's code must give an answer the checker
+// accepts. The result tells the first seed, so the same stress test can be repeated. The programs are compiled once and their executors
+// reused for every seed; a brute-force solution in the job's language shares the solution's
+// sandbox. The result holds the first seed where the code fails, as its only test.
+func stressTest(job Job) JobResult {
+	startTime := time.Now()
+	limits := limitsFor(job)
+
+	seeds := job.Seeds
+	if seeds <= 0 {
+		seeds = defaultStressSeeds
+	}
+	if seeds > maxStressSeeds {
+		seeds = maxStressSeeds
+	}
+	firstSeed := job.Seed
+	if firstSeed <= 0 || firstSeed > maxStressSeed {
+		firstSeed = rand.New(rand.NewSource(time.Now().UnixNano())).Intn(maxStressSeed) + 1
+	}
+
+	result := func(verdict Verdict, outcome runOutcome, message string, tried int) JobResult {
+		return JobResult{
+			JobID:      job.ID,
+			Status:     statusFor(verdict, true),
+			Error:      message,
+			ExecTime:   time.Since(startTime).Milliseconds(),
+			Timestamp:  time.Now(),
+			TestCases:  tried,
+			TotalCases: seeds,
+			UserID:     job.UserID,
+			ProblemID:  job.ProblemID,
+			Language:   job.Language,
+			Verdict:    verdict,
+			ExitCode:   outcome.ExitCode,
+			Signal:     outcome.signal(),
+			Stderr:     outcome.Stderr,
+			Seed:       firstSeed,
+		}
+	}
+	internalError := func(err error) JobResult {
+		return result(VerdictInternalError, runOutcome{}, err.Error(), 0)
+	}
+
+	if job.Generator == nil || job.Generator.Code == "" || job.Brute == nil || job.Brute.Code == "" {
+		return internalError(fmt.Errorf("a stress test needs a generator and a brute-force solution"))
+	}
+
+	// Prepare and compile the programs: the brute-force solution runs under the problem's limits
+	// too, the generator gets the checker's
+	setStage(job.ID, StageCompiling, 0, 0)
+	type program struct {
+		name string // of the executor, and in error messages
+		Program
+		limits Limits
+	}
+	programs := []program{
+		{"solution", Program{Language: job.Language, Code: job.Code}, limits},
+		{"brute", *job.Brute, limits},
+		{"generator", *job.Generator, checkerLimits},
+	}
+	executors := make(map[string]Executor)
+	for _, p := range programs {
+		// Function problems: both solutions are functions, the generator writes their arguments
+		if p.name != "generator" {
+			code, err := programCode(job, p.Language, p.Code)
+			if err != nil {
+				return internalError(fmt.Errorf("%s: %v", p.name, err))
+			}
+			p.Code = code
+		}
+
+		var executor Executor
+		var err error
+		if p.name == "brute" && p.Language == job.Language {
+			executor, err = executors["solution"].Sibling(p.name, p.Code)
+		} else {
+			executor, err = prepareExecutor(job.ID+"-"+p.name, p.Language, p.Code, p.limits)
+			if err == nil {
+				defer executor.Cleanup()
+			}
+		}
+		if err != nil {
+			return internalError(fmt.Errorf("%s: %v", p.name, err))
+		}
+
+		if compile := executor.Compile(); compile.Err != nil || compile.ExitCode != 0 {
+			verdict, message := compileFailure(compile)
+			if p.name != "solution" && verdict == VerdictCompilationError {
+				verdict = VerdictInternalError // the user's code is fine, the stress test is not
+			}
+			return result(verdict, compile, fmt.Sprintf("%s: %s", p.name, message), 0)
+		}
+		executors[p.name] = executor
+	}
+	solution, brute, generator := executors["solution"], executors["brute"], executors["generator"]
+
+	checker, err := newChecker(job)
+	if err != nil {
+		return internalError(err)
+	}
+	if closer, ok := checker.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	var slowestSeed time.Duration
+	for i := 0; i < seeds; i++ {
+		seed := firstSeed + i
+		// Stop before a seed that would likely end past the budget
+		if time.Since(startTime)+slowestSeed > stressTimeBudget {
+			res := result(VerdictAccepted, runOutcome{}, "", i)
+			res.Output = fmt.Sprintf("No difference found in %d seeds from %d (stopped after %s).", i, firstSeed, stressTimeBudget)
+			return res
+		}
+		setStage(job.ID, StageRunning, i+1, seeds)
+		seedStart := time.Now()
+
+		gen := generator.Run(checkerLimits, nil, "ARGS="+strconv.Itoa(seed))
+		if verdict := classifyRun(gen, checkerLimits); verdict != "" {
+			return result(VerdictInternalError, gen, fmt.Sprintf("Seed %d: the generator failed (%s)", seed, verdict), i)
+		}
+		input := gen.Stdout
+
+		expected := brute.Run(limits, strings.NewReader(input))
+		if verdict := classifyRun(expected, limits); verdict != "" {
+			return result(VerdictInternalError, expected, fmt.Sprintf("Seed %d: the brute-force solution failed (%s)", seed, verdict), i)
+		}
+
+		run := solution.Run(limits, strings.NewReader(input))
+		verdict := classifyRun(run, limits)
+		message := ""
+		if verdict == "" {
+			verdict, message = checker.Check(input, expected.Stdout, run.Stdout)
+		}
+		if verdict == VerdictAccepted {
+			if elapsed := time.Since(seedStart); elapsed > slowestSeed {
+				slowestSeed = elapsed
+			}
+			continue
+		}
+
+		// The first failing seed is the answer
+		tc := TestCaseResult{
+			Index:     seed,
+			Verdict:   verdict,
+			TimeMs:    run.Elapsed.Milliseconds(),
+			CPUTimeMs: run.CPUTime.Milliseconds(),
+			MemoryKB:  run.MemoryKB,
+			measured:  run.UsageMeasured,
+			Input:     truncate(input),
+			Expected:  truncate(strings.TrimSpace(expected.Stdout)),
+			Actual:    truncate(strings.TrimSpace(run.Stdout)),
+			Message:   truncate(message),
+		}
+		publishEvent(JobEvent{Type: EventTest, JobID: job.ID, Test: i + 1, Total: seeds, Case: &tc})
+		res := result(verdict, run, "", i)
+		res.Output = fmt.Sprintf("Found a failing input with seed %d.\n%s", seed, tc.describe(limits))
+		res.Tests = []TestCaseResult{tc}
+		return res
+	}
+
+	res := result(VerdictAccepted, runOutcome{}, "", seeds)
+	res.Output = fmt.Sprintf("No difference found in %d seeds from %d.", seeds, firstSeed)
+	return res
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// withoutRedis points the Redis client at a closed port, so job updates fail fast (and are logged)
+func withoutRedis(t *testing.T) {
+	t.Helper()
+	saved := rdb
+	rdb = redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	t.Cleanup(func() {
+		rdb.Close()
+		rdb = saved
+	})
+}
+
+// Programs of the stress tests: the generator writes seed * 7 mod 10 (3 for seed 9)
+const (
+	stressGenerator = "echo $(($1 * 7 % 10))\n"
+	stressBrute     = "read n\necho $((n * 2))\n"
+	stressSolution  = "read n\necho $((n + n))\n"
+	stressWrong     = "read n\nif [ $n -eq 3 ]; then echo 7; else echo $((n + n)); fi\n"
+)
+
+func TestStressTest(t *testing.T) {
+	useLocalExecutor(t)
+	withoutRedis(t)
+	// The same shell under another name, for a brute-force solution that needs its own executor
+	languages["sh-other"] = languages[testLanguage]
+
+	tests := []struct {
+		name      string
+		code      string
+		brute     Program
+		want      Verdict
+		wantSeed  int // of the failing test, 0 for none
+		wantError string
+	}{
+		{"same language, no difference", stressSolution, Program{testLanguage, stressBrute}, VerdictAccepted, 0, ""},
+		{"same language, difference", stressWrong, Program{testLanguage, stressBrute}, VerdictWrongAnswer, 9, ""},
+		{"other language, no difference", stressSolution, Program{"sh-other", stressBrute}, VerdictAccepted, 0, ""},
+		{"other language, difference", stressWrong, Program{"sh-other", stressBrute}, VerdictWrongAnswer, 9, ""},
+		{"failing brute-force solution", stressSolution, Program{testLanguage, "exit 3\n"}, VerdictInternalError, 0, "brute-force solution failed"},
+		{"brute-force solution killed at the problem's time limit", stressSolution, Program{testLanguage, "sleep 5\n"}, VerdictInternalError, 0, "brute-force solution failed (TLE)"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := Job{
+				ID:        fmt.Sprintf("stress-%d", i),
+				Mode:      ModeStress,
+				Language:  testLanguage,
+				Code:      tt.code,
+				TimeLimit: 1000, // ms, wall time 3 s
+				Brute:     &tt.brute,
+				Generator: &Program{testLanguage, stressGenerator},
+				Seeds:     20,
+				Seed:      1,
+			}
+			res := stressTest(job)
+			if res.Verdict != tt.want {
+				t.Fatalf("verdict %q, want %q (%s%s)", res.Verdict, tt.want, res.Error, res.Output)
+			}
+			if !strings.Contains(res.Error, tt.wantError) {
+				t.Errorf("error %q, want it to contain %q", res.Error, tt.wantError)
+			}
+			if tt.wantSeed == 0 {
+				if len(res.Tests) != 0 {
+					t.Errorf("got failing tests %+v", res.Tests)
+				}
+				return
+			}
+			if res.Seed != 1 {
+				t.Errorf("first seed %d, want the job's", res.Seed)
+			}
+			if len(res.Tests) != 1 || res.Tests[0].Index != tt.wantSeed {
+				t.Fatalf("got failing tests %+v, want seed %d", res.Tests, tt.wantSeed)
+			}
+			if tc := res.Tests[0]; tc.Input != "3\n" || tc.Expected != "6" || tc.Actual != "7" {
+				t.Errorf("failing test: input %q, expected %q, actual %q", tc.Input, tc.Expected, tc.Actual)
+			}
+		})
+	}
+}
+
+// Without a seed the stress test starts from a random one, and tells it so the failure can be
+// repeated
+func TestStressTestRandomSeed(t *testing.T) {
+	useLocalExecutor(t)
+	withoutRedis(t)
+	job := Job{
+		ID:        "stress-random",
+		Mode:      ModeStress,
+		Language:  testLanguage,
+		Code:      stressWrong,
+		TimeLimit: 1000,
+		Brute:     &Program{testLanguage, stressBrute},
+		Generator: &Program{testLanguage, stressGenerator},
+		Seeds:     10, // one of any 10 seeds in a row ends in 9
+	}
+	res := stressTest(job)
+	if res.Seed < 1 || res.Seed > maxStressSeed {
+		t.Fatalf("first seed %d, want one in [1, %d]", res.Seed, maxStressSeed)
+	}
+	if res.Verdict != VerdictWrongAnswer || len(res.Tests) != 1 {
+		t.Fatalf("verdict %q with tests %+v, want WA (%s%s)", res.Verdict, res.Tests, res.Error, res.Output)
+	}
+	failing := res.Tests[0].Index
+	if failing < res.Seed || failing >= res.Seed+10 || failing%10 != 9 {
+		t.Errorf("failing seed %d, want the one ending in 9 from %d", failing, res.Seed)
+	}
+	if !strings.Contains(res.Output, fmt.Sprintf("seed %d", failing)) {
+		t.Errorf("output %q does not tell the failing seed", res.Output)
+	}
+
+	// The failing seed alone reproduces the failure
+	job.Seed, job.Seeds = failing, 1
+	if again := stressTest(job); again.Verdict != VerdictWrongAnswer || len(again.Tests) != 1 || again.Tests[0].Index != failing {
+		t.Errorf("seed %d again: verdict %q with tests %+v", failing, again.Verdict, again.Tests)
+	}
+}
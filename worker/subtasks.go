@@ -0,0 +1,107 @@
+package main
+
+// Subtask groups test cases of a problem, IOI-style: its points are earned only if all its tests pass
+type Subtask struct {
+	Number int   `json:"number"`
+	Points int   `json:"points"`
+	Tests  []int `json:"tests"` // 0-based indexes in Job.Inputs
+}
+
+// SubtaskResult is the score of one subtask of a submission
+type SubtaskResult struct {
+	Number  int     `json:"number"`
+	Points  int     `json:"points"` // earned: all or nothing
+	Max     int     `json:"max"`
+	Verdict Verdict `json:"verdict,omitempty"` // first failing verdict, AC if every test passed, empty if not run
+	Passed  int     `json:"passed"`
+	Total   int     `json:"total"`
+}
+
+// scoreTests scores the test results of a job, as a percentage (0-100) of the problem points.
+// Without subtasks the score is all or nothing. Tests that were not run count as failed.
+// Tests outside every subtask, and those of subtasks worth no points (samples, typically), are
+// required by every subtask, and a subtask without tests of its own is passed when they are: a
+// score of 100 always means that every test passed.
+func scoreTests(job Job, tests []TestCaseResult) (float64, []SubtaskResult) {
+	verdictOf := func(i int) Verdict {
+		if i < len(tests) {
+			return tests[i].Verdict
+		}
+		return ""
+	}
+	allPassed := func() bool {
+		for i := range job.Inputs {
+			if verdictOf(i) != VerdictAccepted {
+				return false
+			}
+		}
+		return true
+	}
+
+	if len(job.Subtasks) == 0 {
+		if allPassed() {
+			return 100, nil
+		}
+		return 0, nil
+	}
+
+	scored := make([]bool, len(job.Inputs))
+	for _, subtask := range job.Subtasks {
+		for _, i := range subtask.Tests {
+			if i < len(scored) && subtask.Points > 0 {
+				scored[i] = true
+			}
+		}
+	}
+	var common []int
+	for i, ok := range scored {
+		if !ok {
+			common = append(common, i)
+		}
+	}
+
+	results := make([]SubtaskResult, 0, len(job.Subtasks))
+	earned, total := 0, 0
+	for _, subtask := range job.Subtasks {
+		subtaskTests := subtask.Tests
+		if subtask.Points > 0 {
+			subtaskTests = append(append([]int(nil), subtask.Tests...), common...)
+		}
+		res := SubtaskResult{
+			Number:  subtask.Number,
+			Max:     subtask.Points,
+			Verdict: VerdictAccepted,
+			Total:   len(subtaskTests),
+		}
+		for _, i := range subtaskTests {
+			switch verdict := verdictOf(i); verdict {
+			case VerdictAccepted:
+				res.Passed++
+			case "":
+				// Not run: the job stopped earlier
+				if res.Verdict == VerdictAccepted {
+					res.Verdict = ""
+				}
+			default:
+				if res.Verdict == VerdictAccepted || res.Verdict == "" {
+					res.Verdict = verdict
+				}
+			}
+		}
+		if res.Passed == res.Total {
+			res.Points = subtask.Points
+		}
+		earned += res.Points
+		total += subtask.Points
+		results = append(results, res)
+	}
+
+	// Subtasks worth no points: all or nothing, as without subtasks
+	if total == 0 {
+		if allPassed() {
+			return 100, results
+		}
+		return 0, results
+	}
+	return float64(earned) * 100 / float64(total), results
+}
@@ -0,0 +1,110 @@
+package main
+
+import "testing"
+
+func TestScoreTests(t *testing.T) {
+	const AC, WA = VerdictAccepted, VerdictWrongAnswer
+	tests := []struct {
+		name     string
+		subtasks []Subtask
+		verdicts []Verdict // of the tests that ran; the job has 4 tests
+		want     float64
+	}{
+		{"no subtasks, all passed", nil, []Verdict{AC, AC, AC, AC}, 100},
+		{"no subtasks, one failed", nil, []Verdict{AC, WA, AC, AC}, 0},
+		{"no subtasks, stopped early", nil, []Verdict{AC, AC}, 0},
+		{
+			"all subtasks passed",
+			[]Subtask{{Number: 1, Points: 40, Tests: []int{0, 1}}, {Number: 2, Points: 60, Tests: []int{2, 3}}},
+			[]Verdict{AC, AC, AC, AC}, 100,
+		},
+		{
+			"one subtask failed",
+			[]Subtask{{Number: 1, Points: 40, Tests: []int{0, 1}}, {Number: 2, Points: 60, Tests: []int{2, 3}}},
+			[]Verdict{AC, AC, AC, WA}, 40,
+		},
+		{
+			"test outside the subtasks failed",
+			[]Subtask{{Number: 1, Points: 40, Tests: []int{1}}, {Number: 2, Points: 60, Tests: []int{2, 3}}},
+			[]Verdict{WA, AC, AC, AC}, 0,
+		},
+		{
+			"test outside the subtasks passed",
+			[]Subtask{{Number: 1, Points: 40, Tests: []int{1}}, {Number: 2, Points: 60, Tests: []int{2, 3}}},
+			[]Verdict{AC, AC, WA, AC}, 40,
+		},
+		{
+			// The API sends only the stored tests, so the indexes of the subtasks are theirs
+			"middle subtask failed",
+			[]Subtask{{Number: 1, Points: 50, Tests: []int{0}}, {Number: 2, Points: 40, Tests: []int{1}}, {Number: 3, Points: 10, Tests: []int{2, 3}}},
+			[]Verdict{AC, WA, AC, AC}, 60,
+		},
+		{
+			"empty subtask",
+			[]Subtask{{Number: 1, Points: 50, Tests: []int{0, 1, 2, 3}}, {Number: 2, Points: 50}},
+			[]Verdict{AC, AC, AC, AC}, 100,
+		},
+		{
+			"empty subtask, tests outside the subtasks failed",
+			[]Subtask{{Number: 1, Points: 50, Tests: []int{0, 1, 2}}, {Number: 2, Points: 50}},
+			[]Verdict{AC, AC, AC, WA}, 0,
+		},
+		{
+			"zero-point subtask failed",
+			[]Subtask{{Number: 0, Points: 0, Tests: []int{0}}, {Number: 1, Points: 100, Tests: []int{1, 2, 3}}},
+			[]Verdict{WA, AC, AC, AC}, 0,
+		},
+		{
+			"only zero-point subtasks, all passed",
+			[]Subtask{{Number: 1, Points: 0, Tests: []int{0, 1}}, {Number: 2, Points: 0, Tests: []int{2, 3}}},
+			[]Verdict{AC, AC, AC, AC}, 100,
+		},
+		{
+			"only zero-point subtasks, one failed",
+			[]Subtask{{Number: 1, Points: 0, Tests: []int{0, 1}}, {Number: 2, Points: 0, Tests: []int{2, 3}}},
+			[]Verdict{AC, AC, AC, WA}, 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := Job{Inputs: make([]string, 4), Subtasks: tt.subtasks}
+			results := make([]TestCaseResult, len(tt.verdicts))
+			for i, verdict := range tt.verdicts {
+				results[i].Verdict = verdict
+			}
+			score, _ := scoreTests(job, results)
+			if score != tt.want {
+				t.Errorf("score = %v, want %v", score, tt.want)
+			}
+			allPassed := len(tt.verdicts) == len(job.Inputs)
+			for _, verdict := range tt.verdicts {
+				allPassed = allPassed && verdict == AC
+			}
+			if (score == 100) != allPassed {
+				t.Errorf("score %v although every test passed: %v", score, allPassed)
+			}
+		})
+	}
+}
+
+func TestScoreTestsSubtaskResults(t *testing.T) {
+	job := Job{
+		Inputs:   make([]string, 4),
+		Subtasks: []Subtask{{Number: 1, Points: 30, Tests: []int{1}}, {Number: 2, Points: 70, Tests: []int{2, 3}}},
+	}
+	tests := []TestCaseResult{{Verdict: VerdictAccepted}, {Verdict: VerdictAccepted}, {Verdict: VerdictTimeLimit}}
+	_, results := scoreTests(job, tests)
+
+	want := []SubtaskResult{
+		{Number: 1, Points: 30, Max: 30, Verdict: VerdictAccepted, Passed: 2, Total: 2},
+		{Number: 2, Points: 0, Max: 70, Verdict: VerdictTimeLimit, Passed: 1, Total: 3},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d subtask results, want %d", len(results), len(want))
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Errorf("subtask %d: got %+v, want %+v", want[i].Number, results[i], want[i])
+		}
+	}
+}
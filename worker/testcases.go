@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// How much of the input / outputs is kept in each TestCaseResult
+const maxPreviewBytes = 1024
+
+// TestCaseResult is the outcome of one test case of a submission
+type TestCaseResult struct {
+	Index     int     `json:"index"` // 1-based, in the order the tests were given
+	Verdict   Verdict `json:"verdict"`
+	TimeMs    int64   `json:"time_ms"`     // wall time, including the executor overhead
+	CPUTimeMs int64   `json:"cpu_time_ms"` // CPU time of the program, 0 when the executor does not report it
+	MemoryKB  int64   `json:"memory_kb"`   // peak memory, 0 when the executor does not report it
+	measured  bool    // the executor reported CPUTimeMs and MemoryKB
+	Hidden    bool    `json:"hidden"`
+	Input     string  `json:"input,omitempty"` // truncated; empty for redacted hidden tests
+	Expected  string  `json:"expected,omitempty"`
+	Actual    string  `json:"actual,omitempty"`
+	Message   string  `json:"message,omitempty"` // checker comment, empty for redacted hidden tests
+}
+
+// isHidden tells if test i is hidden from the user
+func (job Job) isHidden(i int) bool {
+	return i < len(job.Hidden) && job.Hidden[i]
+}
+
+// newTestCaseResult builds the per-test entry, redacting hidden tests unless the job may see them
+func newTestCaseResult(job Job, i int, verdict Verdict, run runOutcome) TestCaseResult {
+	tc := TestCaseResult{
+		Index:     i + 1,
+		Verdict:   verdict,
+		TimeMs:    run.Elapsed.Milliseconds(),
+		CPUTimeMs: run.CPUTime.Milliseconds(),
+		MemoryKB:  run.MemoryKB,
+		measured:  run.UsageMeasured,
+		Hidden:    job.isHidden(i),
+	}
+	if !tc.Hidden || job.ShowHidden {
+		tc.Input = truncate(job.Inputs[i])
+		tc.Expected = truncate(strings.TrimSpace(job.Outputs[i]))
+		tc.Actual = truncate(strings.TrimSpace(run.Stdout))
+	}
+	return tc
+}
+
+// redactHidden returns result as a user who may not see hidden tests would get it: the input,
+// outputs and checker comment of hidden tests are cleared, as well as the description and stderr
+// of a failing hidden test. Results of jobs that cannot see hidden tests are returned unchanged.
+func redactHidden(job Job, result JobResult) JobResult {
+	if !job.ShowHidden {
+		return result
+	}
+	tests := make([]TestCaseResult, len(result.Tests))
+	copy(tests, result.Tests)
+	described := false // the first failing test is the one described in Output
+	for i, tc := range tests {
+		firstFailure := !described && tc.Verdict != VerdictAccepted
+		described = described || firstFailure
+		if !tc.Hidden {
+			continue
+		}
+		tests[i].Input, tests[i].Expected, tests[i].Actual, tests[i].Message = "", "", "", ""
+		if firstFailure {
+			l := limitsFor(job)
+			if full := tc.describe(l); strings.HasSuffix(result.Output, full) {
+				result.Output = strings.TrimSuffix(result.Output, full) + tests[i].describe(l)
+			}
+			result.Stderr = ""
+		}
+	}
+	result.Tests = tests
+	return result
+}
+
+// peakUsage returns the most CPU time and memory any of the tests used, and whether the executor
+// reported them for every test
+func peakUsage(tests []TestCaseResult) (cpuTimeMs, memoryKB int64, measured bool) {
+	measured = len(tests) > 0
+	for _, tc := range tests {
+		measured = measured && tc.measured
+		if tc.CPUTimeMs > cpuTimeMs {
+			cpuTimeMs = tc.CPUTimeMs
+		}
+		if tc.MemoryKB > memoryKB {
+			memoryKB = tc.MemoryKB
+		}
+	}
+	return cpuTimeMs, memoryKB, measured
+}
+
+// describe explains why a test case failed, for JobResult.Output
+func (tc TestCaseResult) describe(l Limits) string {
+	switch tc.Verdict {
+	case VerdictWrongAnswer:
+		if tc.Hidden && tc.Input == "" {
+			return fmt.Sprintf("Test #%d failed (hidden test)", tc.Index)
+		}
+		description := fmt.Sprintf("Test #%d failed\nInput: %q\nExpected: %q\nGot: %q", tc.Index, tc.Input, tc.Expected, tc.Actual)
+		if tc.Message != "" {
+			description += "\nChecker: " + tc.Message
+		}
+		return description
+	case VerdictTimeLimit, VerdictMemoryLimit:
+		return fmt.Sprintf("Test #%d exceeded the %s", tc.Index, limitDescription(tc.Verdict, l))
+	case VerdictOutputLimit:
+		return fmt.Sprintf("Test #%d produced more than %d bytes of output", tc.Index, maxOutputBytes)
+	case VerdictRuntimeError:
+		return fmt.Sprintf("Test #%d crashed", tc.Index)
+	}
+	return fmt.Sprintf("Test #%d could not be run", tc.Index)
+}
+
+// truncate shortens s to maxPreviewBytes, marking that it was cut
+func truncate(s string) string {
+	if len(s) <= maxPreviewBytes {
+		return s
+	}
+	cut := maxPreviewBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// An admin's submission is judged with the hidden tests shown, but what is stored (and read later
+// by the users who solved the problem) must not contain them
+func TestRedactHidden(t *testing.T) {
+	useLocalExecutor(t)
+	withoutRedis(t)
+	job := Job{
+		ID:         "redact",
+		Mode:       ModeSubmit,
+		Language:   testLanguage,
+		Code:       "read n\necho \"stderr of $n\" >&2\nif [ $n -eq 4242 ]; then echo 0; else echo $((n * 2)); fi\n",
+		Inputs:     []string{"1\n", "4242\n", "3\n"},
+		Outputs:    []string{"2\n", "8484\n", "6\n"},
+		Hidden:     []bool{false, true, true},
+		ShowHidden: true,
+		RunAll:     true,
+	}
+
+	result := executeCode(job)
+	if result.Verdict != VerdictWrongAnswer || !strings.Contains(result.Output, "8484") {
+		t.Fatalf("the admin got %s: %q, want WA with the hidden test shown", result.Verdict, result.Output)
+	}
+
+	stored := redactHidden(job, result)
+	details, err := json.Marshal(stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"4242", "8484", "stderr of"} {
+		if strings.Contains(string(details), secret) {
+			t.Errorf("the stored details contain %q: %s", secret, details)
+		}
+	}
+	if !strings.HasSuffix(stored.Output, "Test #2 failed (hidden test)") {
+		t.Errorf("stored output %q, want the failure of the hidden test without its data", stored.Output)
+	}
+	if stored.Tests[0].Input != "1\n" || stored.Tests[0].Actual != "2" {
+		t.Errorf("the visible test was redacted: %+v", stored.Tests[0])
+	}
+	if result.Tests[1].Input == "" {
+		t.Error("redactHidden changed the result of the admin")
+	}
+
+	// Users who cannot see hidden tests already got a redacted result
+	job.ShowHidden = false
+	if plain := executeCode(job); strings.Contains(plain.Output, "4242") || plain.Tests[1].Input != "" {
+		t.Errorf("hidden test shown to a user: %q", plain.Output)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// Verdict is the outcome of judging a submission (or a single test case)
+type Verdict string
+
+const (
+	VerdictAccepted         Verdict = "AC"  // output matched the expected output
+	VerdictWrongAnswer      Verdict = "WA"  // output did not match
+	VerdictCompilationError Verdict = "CE"  // the code did not compile
+	VerdictRuntimeError     Verdict = "RE"  // non-zero exit code or crash
+	VerdictTimeLimit        Verdict = "TLE" // CPU or wall time limit exceeded
+	VerdictMemoryLimit      Verdict = "MLE" // memory limit exceeded
+	VerdictOutputLimit      Verdict = "OLE" // too much output
+	VerdictOK               Verdict = "OK"  // ran fine, nothing to compare against (playground)
+	VerdictInternalError    Verdict = "IE"  // the judge itself failed
+)
+
+// Maximum stdout/stderr kept per run; more than this on stdout is an OLE
+const maxOutputBytes = 1 << 20
+
+// Exit code the executors use in PHASE=compile when the compiler rejects the code
+const exitCodeCompileError = 1
+
+// Signal names for the exit codes a shell reports for killed programs (128 + n)
+var signalNames = map[int]string{
+	1:  "SIGHUP",
+	2:  "SIGINT",
+	3:  "SIGQUIT",
+	4:  "SIGILL",
+	6:  "SIGABRT",
+	7:  "SIGBUS",
+	8:  "SIGFPE",
+	9:  "SIGKILL",
+	11: "SIGSEGV",
+	13: "SIGPIPE",
+	15: "SIGTERM",
+	24: "SIGXCPU",
+	25: "SIGXFSZ",
+}
+
+// runOutcome is what came back from one `docker exec` of an executor phase
+type runOutcome struct {
+	Stdout         string
+	Stderr         string
+	ExitCode       int
+	Elapsed        time.Duration // wall time, including the docker exec overhead
+	CPUTime        time.Duration // CPU time of the program, 0 when the executor does not report it
+	MemoryKB       int64         // peak memory (RSS) of the program, 0 when the executor does not report it
+	UsageMeasured  bool          // the executor reported CPUTime and MemoryKB (which can then be 0)
+	Err            error         // error other than a non-zero exit code (docker failure, wall time)
+	OutputOverflow bool          // stdout went over maxOutputBytes
+}
+
+// signal returns the name of the signal that killed the program, if any
+func (o runOutcome) signal() string {
+	if o.ExitCode > 128 {
+		if name, ok := signalNames[o.ExitCode-128]; ok {
+			return name
+		}
+		return fmt.Sprintf("signal %d", o.ExitCode-128)
+	}
+	return ""
+}
+
+// classifyRun decides the verdict of a run before its output is compared.
+// It returns "" when the program finished normally and its output should be checked.
+func classifyRun(o runOutcome, l Limits) Verdict {
+	if errors.Is(o.Err, errWallTimeExceeded) {
+		return VerdictTimeLimit
+	}
+	if o.Err != nil {
+		return VerdictInternalError
+	}
+	// The executors kill the program a little past the limits (whole CPU seconds for ulimit -t,
+	// the container's memory headroom): a measured usage over them fails the test all the same
+	if o.UsageMeasured && l.CPUTime > 0 && o.CPUTime > l.CPUTime {
+		return VerdictTimeLimit
+	}
+	if o.UsageMeasured && l.MemoryMB > 0 && o.MemoryKB > int64(l.MemoryMB)*1024 {
+		return VerdictMemoryLimit
+	}
+	switch o.ExitCode {
+	case 0:
+		if o.OutputOverflow {
+			return VerdictOutputLimit
+		}
+		return ""
+	case exitCodeTimeout, exitCodeSIGXCPU:
+		return VerdictTimeLimit
+	case exitCodeSIGKILL:
+		if o.Elapsed >= l.WallTime {
+			return VerdictTimeLimit
+		}
+		return VerdictMemoryLimit
+	}
+	if o.OutputOverflow {
+		return VerdictOutputLimit
+	}
+	return VerdictRuntimeError
+}
+
+// statusFor maps a verdict to the legacy JobResult.Status values clients already understand
+func statusFor(v Verdict, validate bool) string {
+	switch v {
+	case VerdictAccepted:
+		return "accept"
+	case VerdictOK:
+		return "success"
+	case VerdictTimeLimit:
+		return statusTimeLimit
+	case VerdictMemoryLimit:
+		return statusMemoryLimit
+	case VerdictInternalError:
+		return "error"
+	}
+	if !validate {
+		return "error"
+	}
+	return "fail"
+}
+
+// exitCodeOf extracts the exit code of a finished command, or -1 if it did not exit normally
+func exitCodeOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// cappedBuffer keeps the first maxOutputBytes written to it and remembers if there was more
+type cappedBuffer struct {
+	data     []byte
+	overflow bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	room := maxOutputBytes - len(b.data)
+	if len(p) > room {
+		b.overflow = true
+		if room > 0 {
+			b.data = append(b.data, p[:room]...)
+		}
+		return len(p), nil
+	}
+	b.data = append(b.data, p...)
+	return len(p), nil
+}
+
+func (b *cappedBuffer) String() string {
+	return string(b.data)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestClassifyRunLocal(t *testing.T) {
+	useLocalExecutor(t)
+	limits := Limits{CPUTime: time.Second, WallTime: 3 * time.Second, MemoryMB: 64}
+
+	tests := []struct {
+		name   string
+		script string
+		want   Verdict
+	}{
+		{"normal exit", "echo 42\n", ""},
+		{"output over the limit", "head -c 2000000 /dev/zero\n", VerdictOutputLimit},
+		{"output over the limit then crash", "head -c 2000000 /dev/zero\nexit 1\n", VerdictOutputLimit},
+		{"SIGKILL before the wall time (OOM killer)", "kill -KILL $$\n", VerdictMemoryLimit},
+		{"killed at the wall time", "sleep 10\n", VerdictTimeLimit},
+		{"CPU time limit (SIGXCPU)", "while :; do :; done\n", VerdictTimeLimit},
+		{"timeout exit code", "exit 124\n", VerdictTimeLimit},
+		{"non-zero exit code", "exit 3\n", VerdictRuntimeError},
+		{"SIGSEGV", "kill -SEGV $$\n", VerdictRuntimeError},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := prepareScript(t, fmt.Sprintf("classify-%d", i), tt.script, limits)
+			run := executor.Run(limits, nil)
+			if got := classifyRun(run, limits); got != tt.want {
+				t.Errorf("classifyRun = %q, want %q (exit code %d, err %v, elapsed %v)", got, tt.want, run.ExitCode, run.Err, run.Elapsed)
+			}
+		})
+	}
+}
+
+// The executors report what killed the program only through its exit code, so a SIGKILL that comes
+// after the wall time is the wall time watchdog, not the OOM killer
+func TestClassifyRunSIGKILL(t *testing.T) {
+	limits := Limits{CPUTime: time.Second, WallTime: 3 * time.Second}
+	tests := []struct {
+		name string
+		run  runOutcome
+		want Verdict
+	}{
+		{"before the wall time", runOutcome{ExitCode: exitCodeSIGKILL, Elapsed: time.Second}, VerdictMemoryLimit},
+		{"at the wall time", runOutcome{ExitCode: exitCodeSIGKILL, Elapsed: 3 * time.Second}, VerdictTimeLimit},
+		{"after the wall time", runOutcome{ExitCode: exitCodeSIGKILL, Elapsed: 4 * time.Second}, VerdictTimeLimit},
+		{"wall time error", runOutcome{ExitCode: -1, Err: errWallTimeExceeded}, VerdictTimeLimit},
+		{"docker failure", runOutcome{ExitCode: -1, Err: errors.New("docker: not found")}, VerdictInternalError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyRun(tt.run, limits); got != tt.want {
+				t.Errorf("classifyRun = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// The executors kill the program only past the rounded-up limits, the measured usage decides
+func TestClassifyRunUsage(t *testing.T) {
+	limits := Limits{CPUTime: 1500 * time.Millisecond, WallTime: 4 * time.Second, MemoryMB: 64}
+	tests := []struct {
+		name string
+		run  runOutcome
+		want Verdict
+	}{
+		{"within the limits", runOutcome{CPUTime: 1400 * time.Millisecond, MemoryKB: 60 * 1024, UsageMeasured: true}, ""},
+		{"at the limits", runOutcome{CPUTime: 1500 * time.Millisecond, MemoryKB: 64 * 1024, UsageMeasured: true}, ""},
+		{"CPU time over the limit", runOutcome{CPUTime: 1900 * time.Millisecond, MemoryKB: 1024, UsageMeasured: true}, VerdictTimeLimit},
+		{"memory over the limit", runOutcome{CPUTime: 10 * time.Millisecond, MemoryKB: 94 * 1024, UsageMeasured: true}, VerdictMemoryLimit},
+		{"memory over the limit, crashed", runOutcome{ExitCode: 1, MemoryKB: 65*1024 + 1, UsageMeasured: true}, VerdictMemoryLimit},
+		{"CPU time over the limit, killed", runOutcome{ExitCode: exitCodeSIGXCPU, CPUTime: 2 * time.Second, UsageMeasured: true}, VerdictTimeLimit},
+		{"not measured", runOutcome{CPUTime: 0, MemoryKB: 0}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyRun(tt.run, limits); got != tt.want {
+				t.Errorf("classifyRun = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// A 500 ms limit is a whole second for ulimit -t: the run survives past the limit, but fails the test
+func TestClassifyRunLocalCPUTime(t *testing.T) {
+	useLocalExecutor(t)
+	limits := Limits{CPUTime: 500 * time.Millisecond, WallTime: 3 * time.Second, MemoryMB: 64}
+	// Busy for 800 ms of wall time, nearly all of it on the CPU
+	executor := prepareScript(t, "classify-cpu", "end=$(($(date +%s%N) + 800000000))\nwhile [ $(date +%s%N) -lt $end ]; do :; done\n", limits)
+	run := executor.Run(limits, nil)
+	if run.ExitCode != 0 || run.CPUTime <= limits.CPUTime {
+		t.Skipf("the run did not end past the limit: exit code %d, %v of CPU time", run.ExitCode, run.CPUTime)
+	}
+	if got := classifyRun(run, limits); got != VerdictTimeLimit {
+		t.Errorf("classifyRun = %q, want %q (%v of CPU time)", got, VerdictTimeLimit, run.CPUTime)
+	}
+}